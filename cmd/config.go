@@ -1,97 +1,813 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
-// Config holds the application configuration
+// configVersion is the schema version of the config file written by this version of rip.
+// Files with a newer version are rejected.
+const configVersion = 1
+
+// configFileName is the config file in the home directory.
+const configFileName = ".rip.yaml"
+
+// legacyConfigFileName is the key=value config file used before the YAML config.
+// It is migrated to configFileName the first time rip runs without a YAML config.
+const legacyConfigFileName = ".rip.conf"
+
+// Config holds the application configuration, as stored in ~/.rip.yaml.
 type Config struct {
-	StoragePath string // Path where ripped media will be stored
+	Version         int             `yaml:"version"`          // Schema version of the file (configVersion)
+	StoragePath     string          `yaml:"storage_path"`     // Path where ripped media will be stored
+	StagingPath     string          `yaml:"staging_path"`     // Scratch directory for rips in progress (empty = chosen automatically)
+	PlacementPolicy string          `yaml:"placement_policy"` // MergerFS branch a rip is written to: mfs, epff or lus
+	Device          string          `yaml:"device"`           // Drive used when no --device or --source is given
+	Metadata        MetadataConfig  `yaml:"metadata"`
+	Limits          LimitsConfig    `yaml:"limits"`
+	Movie           MovieConfig     `yaml:"movie"`
+	TV              TVConfig        `yaml:"tv"`
+	FileBot         FileBotConfig   `yaml:"filebot"`
+	Naming          NamingConfig    `yaml:"naming"`
+	Paths           PathsConfig     `yaml:"paths"`
+	Transcode       TranscodeConfig `yaml:"transcode"`
+	Hooks           HooksConfig     `yaml:"hooks"`
+	DefaultProfile  string          `yaml:"default_profile"` // Profile used when --profile is not given (empty = none)
+
+	// Profiles holds the named profiles as written in the file; see applyProfile
+	Profiles map[string]yaml.Node `yaml:"profiles"`
+
+	profile         string            // The profile in use, empty for none
+	profileProblems []string          // Problems of the profiles, found when the file is decoded
+	sources         map[string]string // Where each key's value came from, when not the default
+}
+
+// MetadataConfig selects and configures the metadata lookup backend.
+type MetadataConfig struct {
+	Backend      string `yaml:"backend"`       // Metadata lookup backend: auto, tmdb or filebot
+	TMDBAPIKey   string `yaml:"tmdb_api_key"`  // TMDB v3 API key or v4 read access token
+	TMDBBaseURL  string `yaml:"tmdb_base_url"` // TMDB API base URL (for testing against a stand-in server)
+	TMDBLanguage string `yaml:"tmdb_language"` // Language for TMDB results (e.g. en-US)
+}
+
+// LimitsConfig limits the work shared by all rips running at the same time.
+type LimitsConfig struct {
+	MetadataJobs int `yaml:"metadata_jobs"` // Concurrent metadata lookups across all rips (0 = unlimited)
+	WriteJobs    int `yaml:"write_jobs"`    // Concurrent MakeMKV rips writing to storage across all rips (0 = unlimited)
+}
+
+// MovieConfig holds the tunables of rip dvd.
+type MovieConfig struct {
+	MinLength     time.Duration `yaml:"min_length"`     // Shortest title MakeMKV rips as the movie
+	TitleStrategy string        `yaml:"title_strategy"` // How the movie is chosen among the long titles (see titles.go)
+}
+
+// TVConfig holds the tunables of rip tv.
+type TVConfig struct {
+	MinEpisodeLength time.Duration `yaml:"min_episode_length"` // Shorter titles are not ripped (intros, extras)
+	MaxEpisodeLength time.Duration `yaml:"max_episode_length"` // Longer titles are removed after ripping ("Play All" titles)
+}
+
+// FileBotConfig holds the databases used with FileBot.
+type FileBotConfig struct {
+	MovieDB   string `yaml:"movie_db"`   // Database for movie lookups
+	ShowDB    string `yaml:"show_db"`    // Database for show lookups
+	EpisodeDB string `yaml:"episode_db"` // Database for episode titles
 }
 
-// LoadConfig loads the configuration from ~/.rip.conf or creates it if it doesn't exist
-func LoadConfig() *Config {
+// NamingConfig holds the templates rip names folders and files with (see naming.go).
+// Templates use Go template syntax, e.g. "{{.Title}} ({{.Year}})"; an empty template
+// comes from the preset.
+type NamingConfig struct {
+	Preset       string `yaml:"preset"`        // Templates used where none is given: plex or jellyfin
+	MovieFolder  string `yaml:"movie_folder"`  // Folder of a movie under its category
+	MovieFile    string `yaml:"movie_file"`    // File name of a movie, without .mkv
+	ShowFolder   string `yaml:"show_folder"`   // Folder of a show under storage_path; may contain /
+	SeasonFolder string `yaml:"season_folder"` // Folder of a season under the show (only .Season is set)
+	EpisodeFile  string `yaml:"episode_file"`  // File name of an episode, without .mkv; must contain SxxEyy
+}
+
+// TranscodeConfig sets up re-encoding of ripped files with HandBrake before they go into the library.
+type TranscodeConfig struct {
+	Preset string `yaml:"preset"` // HandBrakeCLI preset, e.g. "H.265 MKV 1080p30" (empty = keep the files MakeMKV writes)
+}
+
+// PathsConfig holds the rules that make folder and file names safe to create (see sanitize.go).
+type PathsConfig struct {
+	Replace   [][]string `yaml:"replace"`    // [text, replacement] pairs applied to every name, in order
+	Unicode   string     `yaml:"unicode"`    // Unicode normalization of names: nfc, nfd or none
+	MaxLength int        `yaml:"max_length"` // Longest folder or file name, in bytes
+}
+
+// HooksConfig holds the commands run at points of a rip.
+type HooksConfig struct {
+	// Commands run after a rip is in the library, each as a program and its arguments.
+	// {kind}, {dir}, {title} and {profile} in the arguments are replaced; see runPostRipHooks.
+	PostRip [][]string `yaml:"post_rip"`
+}
+
+// defaultConfig returns the configuration used for everything the config file does not set.
+func defaultConfig() *Config {
+	return &Config{
+		Version:         configVersion,
+		StoragePath:     "/plex/storage",
+		PlacementPolicy: placementMostFree,
+		Device:          "/dev/sr0",
+		Metadata: MetadataConfig{
+			Backend:      metadataBackendAuto,
+			TMDBBaseURL:  defaultTMDBBaseURL,
+			TMDBLanguage: "en-US",
+		},
+		Limits: LimitsConfig{
+			MetadataJobs: 1,
+			WriteJobs:    2,
+		},
+		Movie: MovieConfig{
+			MinLength:     time.Hour,
+			TitleStrategy: titleStrategyLongest,
+		},
+		TV: TVConfig{
+			MinEpisodeLength: 10 * time.Minute,
+			MaxEpisodeLength: 65 * time.Minute,
+		},
+		FileBot: FileBotConfig{
+			MovieDB:   "TheMovieDB",
+			ShowDB:    "TheMovieDB::TV",
+			EpisodeDB: "TheTVDB",
+		},
+		Naming: NamingConfig{
+			Preset: namingPresetPlex,
+		},
+		Paths: PathsConfig{
+			Replace:   defaultPathsReplace,
+			Unicode:   unicodeNFC,
+			MaxLength: 255,
+		},
+	}
+}
+
+// Sources of config values shown by rip config show, in order of precedence (lowest first).
+const (
+	configSourceDefault = "default"
+	configSourceFile    = "file"
+	configSourceProfile = "profile"
+	configSourceEnv     = "env"
+	configSourceFlag    = "flag"
+)
+
+// configEnvPrefix starts the environment variables that override config keys, e.g. RIP_STORAGE_PATH.
+const configEnvPrefix = "RIP_"
+
+// configPathEnv is the environment variable that selects the config file when --config is not given.
+const configPathEnv = configEnvPrefix + "CONFIG"
+
+// LoadConfig loads the configuration from the config file (~/.rip.yaml unless --config or RIP_CONFIG
+// selects another) and applies the RIP_* environment variables and the --storage and --set flags over it.
+// If the file does not exist, an existing ~/.rip.conf is migrated to it, or a default file is created.
+//
+// Returns the configuration, or an error if the file cannot be read, has keys rip does not know
+// or has invalid values, or an override has an invalid value.
+func LoadConfig() (*Config, error) {
 	configPath := getConfigPath()
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		if legacyPath := getLegacyConfigPath(); !configPathOverridden() && fileExists(legacyPath) {
+			if err := migrateLegacyConfig(legacyPath, configPath); err != nil {
+				return nil, err
+			}
+		} else {
+			createDefaultConfig(configPath, defaultConfig())
+		}
+	}
 
-	config := &Config{
-		StoragePath: "/plex/storage", // Default value
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %v", err)
+	}
+	config, err := decodeConfig(content)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %v", configPath, err)
+	}
+	if err := config.applyOverrides(); err != nil {
+		return nil, err
+	}
+	// The profile is chosen once the overrides are known (they may set default_profile),
+	// and the overrides are applied again so they still win over the profile
+	if name := config.selectedProfile(); name != "" {
+		if err := config.applyProfile(name); err != nil {
+			return nil, err
+		}
+		if err := config.applyOverrides(); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.Validate(); err != nil {
+		if config.overridden() {
+			return nil, fmt.Errorf("config file %s with the RIP_* and flag overrides: %v", configPath, err)
+		}
+		return nil, fmt.Errorf("config file %s: %v", configPath, err)
 	}
+	return config, nil
+}
 
-	// Try to read existing config file
-	if _, err := os.Stat(configPath); err == nil {
-		content, err := os.ReadFile(configPath)
-		if err != nil {
-			log.Printf("Warning: Could not read config file: %v\n", err)
-			return config
+// applyOverrides sets the keys given by RIP_* environment variables, then those given by the
+// --storage and --set flags, so flags win over the environment and the environment over the file.
+// Returns every override with an unknown key or a value of the wrong type.
+func (c *Config) applyOverrides() error {
+	var errs []error
+	for _, key := range configKeys() {
+		if key == "version" {
+			continue
 		}
+		if value, ok := os.LookupEnv(configEnvName(key)); ok {
+			if err := c.override(key, value, configSourceEnv); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", configEnvName(key), err))
+			}
+		}
+	}
 
-		// Parse config file
-		lines := strings.Split(string(content), "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			// Skip empty lines and comments
-			if line == "" || strings.HasPrefix(line, "#") {
+	if storageFlag != "" {
+		if err := c.override("storage_path", storageFlag, configSourceFlag); err != nil {
+			errs = append(errs, fmt.Errorf("--storage: %v", err))
+		}
+	}
+	for _, setting := range setFlags {
+		key, value, ok := strings.Cut(setting, "=")
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("--set %q: expected key=value", setting))
+		case key == "version":
+			errs = append(errs, fmt.Errorf("--set %q: version is the schema version of the file and cannot be overridden", setting))
+		default:
+			if err := c.override(key, value, configSourceFlag); err != nil {
+				errs = append(errs, fmt.Errorf("--set %q: %v", setting, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// override sets a key from the environment or a flag and records where it came from.
+// Paths may start with ~/ like in the config file.
+func (c *Config) override(key, value, source string) error {
+	if err := c.Set(key, value); err != nil {
+		return err
+	}
+	if c.sources == nil {
+		c.sources = map[string]string{}
+	}
+	c.sources[key] = source
+	c.StoragePath = expandHome(c.StoragePath)
+	c.StagingPath = expandHome(c.StagingPath)
+	return nil
+}
+
+// overridden reports whether any key was set by an environment variable or flag.
+func (c *Config) overridden() bool {
+	for _, source := range c.sources {
+		if source == configSourceEnv || source == configSourceFlag {
+			return true
+		}
+	}
+	return false
+}
+
+// configEnvName returns the environment variable that overrides a key,
+// e.g. RIP_LIMITS_WRITE_JOBS for limits.write_jobs.
+func configEnvName(key string) string {
+	return configEnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// decodeConfig decodes a YAML config file over the defaults without validating the values.
+// Keys that are not part of the schema are errors, so typos do not go unnoticed.
+func decodeConfig(content []byte) (*Config, error) {
+	config := defaultConfig()
+	config.Version = 0
+	dec := yaml.NewDecoder(strings.NewReader(string(content)))
+	dec.KnownFields(true)
+	if err := dec.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err == nil && len(doc.Content) > 0 {
+		config.sources = map[string]string{}
+		markFileKeys(doc.Content[0], "", config.sources)
+	}
+
+	switch {
+	case config.Version == 0:
+		return nil, fmt.Errorf("missing version (this rip writes version %d)", configVersion)
+	case config.Version > configVersion:
+		return nil, fmt.Errorf("version %d is newer than this rip supports (%d); please update rip", config.Version, configVersion)
+	}
+
+	config.StoragePath = expandHome(config.StoragePath)
+	config.StagingPath = expandHome(config.StagingPath)
+	config.profileProblems = config.checkProfiles()
+	return config, nil
+}
+
+// Validate checks every value of the configuration.
+// Returns all problems found, one per line, or nil if the configuration is valid.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.StoragePath == "" || !filepath.IsAbs(c.StoragePath) {
+		invalid("storage_path must be an absolute path, got %q", c.StoragePath)
+	}
+	if c.StagingPath != "" && !filepath.IsAbs(c.StagingPath) {
+		invalid("staging_path must be an absolute path or empty, got %q", c.StagingPath)
+	}
+	if !slices.Contains(placementPolicies, c.PlacementPolicy) {
+		invalid("placement_policy must be one of %s, got %q", strings.Join(placementPolicies, ", "), c.PlacementPolicy)
+	}
+	if c.Device == "" {
+		invalid("device must not be empty")
+	}
+	if _, ok := c.Profiles[c.DefaultProfile]; c.DefaultProfile != "" && !ok {
+		invalid("default_profile %q is not one of the profiles (%s)", c.DefaultProfile, c.profileNames())
+	}
+
+	switch c.Metadata.Backend {
+	case metadataBackendAuto, metadataBackendTMDB, metadataBackendFileBot:
+	default:
+		invalid("metadata.backend must be auto, tmdb or filebot, got %q", c.Metadata.Backend)
+	}
+	if u, err := url.Parse(c.Metadata.TMDBBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		invalid("metadata.tmdb_base_url must be an http or https URL, got %q", c.Metadata.TMDBBaseURL)
+	}
+	if c.Metadata.Backend == metadataBackendTMDB && c.Metadata.TMDBAPIKey == "" {
+		invalid("metadata.tmdb_api_key must be set when metadata.backend is tmdb")
+	}
+
+	if c.Limits.MetadataJobs < 0 {
+		invalid("limits.metadata_jobs must be 0 (unlimited) or more, got %d", c.Limits.MetadataJobs)
+	}
+	if c.Limits.WriteJobs < 0 {
+		invalid("limits.write_jobs must be 0 (unlimited) or more, got %d", c.Limits.WriteJobs)
+	}
+
+	if c.Movie.MinLength < 0 {
+		invalid("movie.min_length must not be negative, got %s", shortDuration(c.Movie.MinLength))
+	}
+	if !isTitleStrategy(c.Movie.TitleStrategy) {
+		invalid("movie.title_strategy must be one of %s, got %q", strings.Join(titleStrategies, ", "), c.Movie.TitleStrategy)
+	}
+	if c.TV.MinEpisodeLength <= 0 {
+		invalid("tv.min_episode_length must be positive, got %s", shortDuration(c.TV.MinEpisodeLength))
+	}
+	if c.TV.MaxEpisodeLength <= c.TV.MinEpisodeLength {
+		invalid("tv.max_episode_length (%s) must be longer than tv.min_episode_length (%s)", shortDuration(c.TV.MaxEpisodeLength), shortDuration(c.TV.MinEpisodeLength))
+	}
+
+	for _, key := range []string{"filebot.movie_db", "filebot.show_db", "filebot.episode_db"} {
+		if value, _ := c.Get(key); strings.TrimSpace(value) == "" {
+			invalid("%s must not be empty", key)
+		}
+	}
+	for _, problem := range c.Naming.validate() {
+		invalid("%s", problem)
+	}
+	for _, problem := range c.Paths.validate() {
+		invalid("%s", problem)
+	}
+
+	for i, command := range c.Hooks.PostRip {
+		if len(command) == 0 || strings.TrimSpace(command[0]) == "" {
+			invalid("hooks.post_rip command %d must start with the program to run", i+1)
+		}
+	}
+	for _, problem := range c.profileProblems {
+		invalid("%s", problem)
+	}
+	return errors.Join(errs...)
+}
+
+// markFileKeys records every key set in a decoded config file mapping as coming from the file.
+func markFileKeys(node *yaml.Node, prefix string, sources map[string]string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := prefix + node.Content[i].Value
+		if value := node.Content[i+1]; value.Kind == yaml.MappingNode {
+			markFileKeys(value, key+".", sources)
+		} else {
+			sources[key] = configSourceFile
+		}
+	}
+}
+
+// Source returns where the value of a key came from: "default", "file", "env" or "flag".
+func (c *Config) Source(key string) string {
+	if source := c.sources[key]; source != "" {
+		return source
+	}
+	return configSourceDefault
+}
+
+// configKeys returns the dotted names of every config key, such as "limits.write_jobs", in file order.
+func configKeys() []string {
+	var keys []string
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
 				continue
 			}
+			if field.Type.Kind() == reflect.Struct {
+				walk(field.Type, prefix+name+".")
+			} else if field.Type.Kind() != reflect.Map {
+				keys = append(keys, prefix+name)
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+	return keys
+}
 
-			// Parse key=value pairs
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				key := strings.TrimSpace(parts[0])
-				value := strings.TrimSpace(parts[1])
-
-				if key == "storage_path" {
-					// Expand ~ to home directory
-					if strings.HasPrefix(value, "~/") {
-						home, _ := os.UserHomeDir()
-						value = filepath.Join(home, value[2:])
-					}
-					config.StoragePath = value
-				}
+// field returns the struct field holding a dotted config key.
+func (c *Config) field(key string) (reflect.Value, error) {
+	v := reflect.ValueOf(c).Elem()
+	for _, part := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("unknown config key %q (see rip config show)", key)
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+			if name != "" && name == part {
+				v, found = v.Field(i), true
+				break
 			}
 		}
-		return config
+		if !found {
+			return reflect.Value{}, fmt.Errorf("unknown config key %q (see rip config show)", key)
+		}
+	}
+	if v.Kind() == reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%q is a section; give one of its keys, e.g. %s.%s", key, key, strings.Split(v.Type().Field(0).Tag.Get("yaml"), ",")[0])
+	}
+	if v.Kind() == reflect.Map {
+		return reflect.Value{}, fmt.Errorf("%q is edited in the config file; select a profile with --profile and see rip config show", key)
+	}
+	return v, nil
+}
+
+// Get returns the value of a dotted config key as it is written in the config file,
+// e.g. "2" for limits.write_jobs, "1h5m" for tv.max_episode_length or [["plex-scan"]] for hooks.post_rip.
+func (c *Config) Get(key string) (string, error) {
+	v, err := c.field(key)
+	if err != nil {
+		return "", err
+	}
+	if d, ok := v.Interface().(time.Duration); ok {
+		return shortDuration(d), nil
+	}
+	if v.Kind() == reflect.Int {
+		return strconv.Itoa(int(v.Int())), nil
+	}
+	if v.Kind() == reflect.Slice {
+		if v.Len() == 0 {
+			return "[]", nil
+		}
+		// Without HTML escaping, so "<" stays "<" rather than "\u003c"
+		var buf strings.Builder
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		err := enc.Encode(v.Interface())
+		return strings.TrimSuffix(buf.String(), "\n"), err
 	}
+	return v.String(), nil
+}
 
-	// Config file doesn't exist, create it with defaults
-	createDefaultConfig(configPath, config)
-	return config
+// Set parses value for a dotted config key and stores it. Durations are written like 1h or 10m,
+// and lists like [["plex-scan", "--section", "Movies"]].
+// The configuration is not validated; call Validate afterwards.
+func (c *Config) Set(key, value string) error {
+	v, err := c.field(key)
+	if err != nil {
+		return err
+	}
+	if _, ok := v.Interface().(time.Duration); ok {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a duration like 1h, 10m or 1h5m, got %q", key, value)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if v.Kind() == reflect.Int {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number, got %q", key, value)
+		}
+		v.SetInt(int64(n))
+		return nil
+	}
+	if v.Kind() == reflect.Slice {
+		list := reflect.New(v.Type())
+		if err := yaml.Unmarshal([]byte(value), list.Interface()); err != nil {
+			return fmt.Errorf("%s must be a list like [[\"program\", \"argument\"]], got %q", key, value)
+		}
+		v.Set(list.Elem())
+		return nil
+	}
+	v.SetString(value)
+	return nil
 }
 
-// getConfigPath returns the path to the config file
+// getConfigPath returns the path to the config file: the --config flag, else RIP_CONFIG, else ~/.rip.yaml.
 func getConfigPath() string {
+	if cfgFile != "" {
+		return expandHome(cfgFile)
+	}
+	if path := os.Getenv(configPathEnv); path != "" {
+		return expandHome(path)
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		log.Fatalf("Error getting home directory: %v", err)
 	}
-	return filepath.Join(home, ".rip.conf")
+	return filepath.Join(home, configFileName)
+}
+
+// configPathOverridden reports whether the config file was chosen with --config or RIP_CONFIG.
+func configPathOverridden() bool {
+	return cfgFile != "" || os.Getenv(configPathEnv) != ""
+}
+
+// getLegacyConfigPath returns the path of the key=value config file used before ~/.rip.yaml.
+func getLegacyConfigPath() string {
+	return filepath.Join(filepath.Dir(getConfigPath()), legacyConfigFileName)
+}
+
+// migrateLegacyConfig converts a key=value ~/.rip.conf to ~/.rip.yaml.
+// The old file is renamed to ~/.rip.conf.migrated so it is clear that it is no longer read.
+func migrateLegacyConfig(legacyPath, configPath string) error {
+	content, err := os.ReadFile(legacyPath)
+	if err != nil {
+		return fmt.Errorf("error reading %s for migration: %v", legacyPath, err)
+	}
+	config, err := parseLegacyConfig(string(content))
+	if err != nil {
+		return fmt.Errorf("error migrating %s: %v", legacyPath, err)
+	}
+	if err := writeConfigFile(configPath, config); err != nil {
+		return err
+	}
+	if err := os.Rename(legacyPath, legacyPath+".migrated"); err != nil {
+		fmt.Printf("Warning: Could not rename %s: %v\n", legacyPath, err)
+	}
+	fmt.Printf("Migrated %s to %s (the old file was renamed to %s.migrated)\n", legacyPath, configPath, legacyConfigFileName)
+	return nil
+}
+
+// parseLegacyConfig parses the key=value format of ~/.rip.conf over the defaults.
+// storage_path was the only key of that format; other keys and bad values are errors,
+// as in the YAML config.
+func parseLegacyConfig(content string) (*Config, error) {
+	config := defaultConfig()
+	var errs []error
+	for n, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Parse key=value pairs
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			errs = append(errs, fmt.Errorf("line %d: expected key=value, got %q", n+1, line))
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		switch key {
+		case "storage_path":
+			config.StoragePath = expandHome(value)
+		default:
+			errs = append(errs, fmt.Errorf("line %d: unknown key %q", n+1, key))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// expandHome expands a leading ~/ to the home directory.
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
+
+// fileExists reports whether path exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// getConfigDir returns the directory for rip's state files (such as the watch queue),
+// creating it if needed: ~/.config/rip on Linux, ~/Library/Application Support/rip on macOS.
+func getConfigDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding the user config directory: %v", err)
+	}
+	dir := filepath.Join(base, "rip")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating config directory %s: %v", dir, err)
+	}
+	return dir, nil
 }
 
 // createDefaultConfig creates a default config file
 func createDefaultConfig(configPath string, config *Config) {
+	if err := writeConfigFile(configPath, config); err != nil {
+		log.Printf("Warning: Could not create default config file: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Created default config file at: %s\n", configPath)
+	fmt.Printf("You can edit this file to customize your storage location\n")
+}
+
+// writeConfigFile writes a commented config file with the values of config.
+func writeConfigFile(configPath string, config *Config) error {
+	q := strconv.Quote
+	postRip, err := config.Get("hooks.post_rip")
+	if err != nil {
+		return err
+	}
+	replace, err := config.Get("paths.replace")
+	if err != nil {
+		return err
+	}
 	content := `# rip configuration file
 # This file is automatically created if it doesn't exist
+# Durations are written like 1h, 10m or 1h5m
+
+# Schema version of this file; do not change
+version: ` + strconv.Itoa(configVersion) + `
 
 # Storage path where ripped media will be organized
 # Default: /plex/storage (your MergerFS mount point)
 # You can change this to any directory where you want media stored
 # Example: /mnt/media or ~/Videos/Rips
-storage_path=/plex/storage
+storage_path: ` + q(config.StoragePath) + `
+
+# Scratch directory where rips are written and renamed before they are moved into the library
+# Leave empty to use .rip-staging on the MergerFS disk the rip is placed on
+# (or in storage_path when it is not a MergerFS pool), so the final move is a rename
+staging_path: ` + q(config.StagingPath) + `
+
+# Which MergerFS disk a rip is written to when storage_path is a MergerFS pool
+# mfs: the disk with the most free space
+# epff: the first disk that already has the show or movie folder, so every season of a show
+#       stays on one disk (new shows and movies go to the disk with the most free space)
+# lus: the disk with the least used space
+placement_policy: ` + config.PlacementPolicy + `
+
+# Drive used when no --device or --source is given
+device: ` + q(config.Device) + `
+
+metadata:
+  # Metadata lookup backend: auto, tmdb or filebot
+  # auto uses the built-in TMDB client when tmdb_api_key is set, otherwise FileBot
+  backend: ` + config.Metadata.Backend + `
+  # TMDB API key (free at https://www.themoviedb.org/settings/api)
+  # Either the v3 API key or the v4 read access token works
+  tmdb_api_key: ` + q(config.Metadata.TMDBAPIKey) + `
+  tmdb_base_url: ` + q(config.Metadata.TMDBBaseURL) + `
+  # Language for TMDB titles and overviews
+  tmdb_language: ` + q(config.Metadata.TMDBLanguage) + `
+
+# Limits shared by all rips running at the same time (e.g. one per drive); 0 means unlimited
+limits:
+  # How many metadata lookups may run at once
+  metadata_jobs: ` + strconv.Itoa(config.Limits.MetadataJobs) + `
+  # How many MakeMKV rips may write to storage at once
+  write_jobs: ` + strconv.Itoa(config.Limits.WriteJobs) + `
+
+movie:
+  # Shortest title ripped as the movie
+  min_length: ` + shortDuration(config.Movie.MinLength) + `
+  # How the movie is chosen among the titles at least min_length long:
+  # longest, largest, most-chapters, segments (longest after skipping decoy and
+  # Play All titles) or interactive
+  title_strategy: ` + q(config.Movie.TitleStrategy) + `
+
+tv:
+  # Titles shorter than this are not ripped (intros, menus, extras)
+  min_episode_length: ` + shortDuration(config.TV.MinEpisodeLength) + `
+  # Titles longer than this are removed after ripping ("Play All" titles)
+  max_episode_length: ` + shortDuration(config.TV.MaxEpisodeLength) + `
+
+# Databases used with FileBot when it is the metadata backend
+filebot:
+  movie_db: ` + q(config.FileBot.MovieDB) + `
+  show_db: ` + q(config.FileBot.ShowDB) + `
+  episode_db: ` + q(config.FileBot.EpisodeDB) + `
+
+# How folders and files are named, as Go templates (see the README for the fields)
+# Fields: .Title .Year .TMDBID .IMDBID .TVDBID .Genre .Season .Episode .EpisodeTitle .Edition .Resolution
+# Functions: pad (e.g. {{pad .Episode 2}} -> 05), camel, lower, upper
+# Empty templates come from the preset: plex or jellyfin
+naming:
+  preset: ` + config.Naming.Preset + `
+  # e.g. "{{.Title}} ({{.Year}})"
+  movie_folder: ` + q(config.Naming.MovieFolder) + `
+  # e.g. "{{.Title}} ({{.Year}}){{with .Edition}} {edition-{{.}}}{{end}}"
+  movie_file: ` + q(config.Naming.MovieFile) + `
+  # May contain / for more folders, e.g. "{{camel .Genre}}/{{.Title}} ({{.Year}}) {tmdb-{{.TMDBID}}}"
+  show_folder: ` + q(config.Naming.ShowFolder) + `
+  # Only .Season is set, e.g. "Season {{pad .Season 2}}"
+  season_folder: ` + q(config.Naming.SeasonFolder) + `
+  # Must contain S{{pad .Season 2}}E{{pad .Episode 2}}, which later discs are numbered from
+  episode_file: ` + q(config.Naming.EpisodeFile) + `
+
+# How names from metadata and templates are made safe for every filesystem (including SMB and NTFS)
+# Slashes, control characters, leading and trailing dots and ".." are always removed
+paths:
+  # [text, replacement] pairs applied to every folder and file name, in order
+  replace: ` + replace + `
+  # Unicode normalization of names: nfc, nfd (macOS) or none
+  unicode: ` + config.Paths.Unicode + `
+  # Longest folder or file name in bytes; longer names are cut
+  max_length: ` + strconv.Itoa(config.Paths.MaxLength) + `
+
+transcode:
+  # HandBrakeCLI preset used to re-encode rips before they go into the library,
+  # e.g. "H.265 MKV 1080p30" (see HandBrakeCLI --preset-list); empty keeps MakeMKV's files
+  preset: ` + q(config.Transcode.Preset) + `
+
+hooks:
+  # Commands run after a rip is in the library, each as [program, arguments...] (no shell).
+  # {kind}, {dir}, {title} and {profile} in the arguments are replaced.
+  # Example: [["/usr/local/bin/plex-scan", "--dir", "{dir}"]]
+  post_rip: ` + postRip + `
+
+# Named profiles for separate libraries. A profile can set storage_path, staging_path, movie, tv,
+# filebot, naming, paths, transcode and hooks; everything it leaves out comes from the settings above.
+# Use one with --profile NAME (or RIP_PROFILE=NAME)
+# Example:
+#   profiles:
+#     kids:
+#       storage_path: "/mnt/kids"
+#     anime:
+#       storage_path: "/mnt/anime"
+#       tv:
+#         max_episode_length: 30m
+profiles: {}
+
+# Profile used when --profile is not given; empty uses the settings above
+default_profile: ` + q(config.DefaultProfile) + `
 `
 
-	err := os.WriteFile(configPath, []byte(content), 0644)
-	if err != nil {
-		log.Printf("Warning: Could not create default config file at %s: %v\n", configPath, err)
-		return
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("error writing config file %s: %v", configPath, err)
 	}
+	return nil
+}
 
-	fmt.Printf("Created default config file at: %s\n", configPath)
-	fmt.Printf("You can edit this file to customize your storage location\n")
+// shortDuration formats a duration without zero minutes and seconds, e.g. "1h" or "1h5m"
+// rather than "1h0m0s" and "1h5m0s".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
 }
 
 // VerifyStoragePath checks if the storage path exists and is writable
@@ -122,7 +838,8 @@ func VerifyStoragePath(storagePath string) error {
 }
 
 // GetMergerFSDisks reads /etc/fstab and returns the list of disks in the MergerFS pool
-// that mounts at the specified mount point (e.g., /plex/storage)
+// that mounts at the specified mount point (e.g., /plex/storage) and can be written to
+// (see parseMergerFSBranches).
 // Returns empty slice if MergerFS is not found for that mount point
 func GetMergerFSDisks() []string {
 	return getMergerFSDisksForPath(AppConfig.StoragePath)
@@ -157,16 +874,42 @@ func getMergerFSDisksForPath(mountPath string) []string {
 
 		// Check if this line matches our mount point and is mergerfs
 		if mountpoint == mountPath && strings.Contains(fstype, "mergerfs") {
-			// Format: /mnt/disk1:/mnt/disk2:/mnt/disk3 /plex/storage fuse.mergerfs ...
-			// Split the disk list by colons
-			disks := strings.Split(device, ":")
-			return disks
+			// Format: /mnt/disk1:/mnt/disk*=RW:/mnt/archive=NC /plex/storage fuse.mergerfs ...
+			return parseMergerFSBranches(device)
 		}
 	}
 
 	return nil
 }
 
+// parseMergerFSBranches returns the branches of a MergerFS branch list that rips may be written to.
+// Branches are separated by colons and may be globs (/mnt/disk*), which are expanded, and may end
+// in a mode: =RW (the default) is kept, while =RO (read-only) and =NC (no create) branches are
+// left out, since mergerfs does not create files on them either. A mode may be followed by a
+// per-branch minimum free space (=RW,100G), which is ignored. Spaces written as \040 in fstab
+// are unescaped.
+func parseMergerFSBranches(list string) []string {
+	var branches []string
+	for _, branch := range strings.Split(list, ":") {
+		path, mode, _ := strings.Cut(strings.ReplaceAll(branch, `\040`, " "), "=")
+		mode, _, _ = strings.Cut(mode, ",")
+		if path == "" || strings.EqualFold(mode, "RO") || strings.EqualFold(mode, "NC") {
+			continue
+		}
+		if !strings.ContainsAny(path, "*?[") {
+			branches = append(branches, path)
+			continue
+		}
+		matches, _ := filepath.Glob(path)
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.IsDir() && !slices.Contains(branches, m) {
+				branches = append(branches, m)
+			}
+		}
+	}
+	return branches
+}
+
 // DiskSpace represents information about a disk's available space
 type DiskSpace struct {
 	Path      string
@@ -181,8 +924,8 @@ func GetDiskWithMostSpace(diskPaths []string) (string, error) {
 		return "", fmt.Errorf("no disk paths provided")
 	}
 
-	const minFreeSpaceGB = 5
-	const minFreeSpaceBytes = minFreeSpaceGB * 1024 * 1024 * 1024
+	const minFreeSpaceGB = minBranchFreeSpace / (1024 * 1024 * 1024)
+	const minFreeSpaceBytes = minBranchFreeSpace
 
 	var maxSpace uint64
 	var selectedDisk string
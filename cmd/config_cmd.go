@@ -0,0 +1,428 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// allowInvalidConfig is the annotation of commands that run even when the config file is invalid,
+// so that it can be checked and fixed with rip itself.
+const allowInvalidConfig = "allow-invalid-config"
+
+// configCmd represents the `config` command, the parent of the commands that read and change ~/.rip.yaml.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show, change and check the configuration",
+	Long: `Show, change and check the configuration in ~/.rip.yaml.
+
+Keys are written with dots for nested settings, e.g. storage_path, limits.write_jobs or
+tv.max_episode_length. Run rip config show to list them all.
+
+Every key can be overridden without changing the file, by an environment variable named
+after it (RIP_STORAGE_PATH, RIP_LIMITS_WRITE_JOBS, ...) or by --set key=value.
+Flags win over the environment, and the environment over the file.`,
+}
+
+// configShowCmd represents the `config show` command, which prints the effective configuration.
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective configuration and where each value comes from",
+	Long: `Show every configuration key with its effective value and where the value comes from:
+"default" when nothing sets it, "file" when the config file does, "profile" when the profile in use
+does, "env" when a RIP_* environment variable overrides it and "flag" when --storage or --set does.
+Use --profile to see the configuration of a profile.
+The TMDB API key is not printed; use rip config get metadata.tmdb_api_key to see it.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runConfigShow,
+}
+
+// configGetCmd represents the `config get` command, which prints one value.
+var configGetCmd = &cobra.Command{
+	Use:          "get <key>",
+	Short:        "Print the value of a configuration key",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runConfigGet,
+}
+
+// configSetCmd represents the `config set` command, which changes one value in ~/.rip.yaml.
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change the value of a configuration key in ~/.rip.yaml",
+	Long: `Change the value of a configuration key in ~/.rip.yaml.
+
+Only the line of the key is changed, so the comments in the file are kept. The new value is
+checked first: a change that makes the configuration invalid is refused and the file is left as it was.
+Durations are written like 1h, 10m or 1h5m.
+
+Example:
+  rip config set storage_path /mnt/media
+  rip config set tv.max_episode_length 70m`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	Annotations:  map[string]string{allowInvalidConfig: "true"},
+	RunE:         runConfigSet,
+}
+
+// configValidateCmd represents the `config validate` command, which checks the configuration
+// and the system it describes.
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration, the storage path, the external tools and the MergerFS pool",
+	Long: `Check that rip is ready to rip:
+
+  - ~/.rip.yaml can be read and every value is valid
+  - storage_path (and staging_path, when set) exists and is writable
+  - the external tools are installed: makemkvcon, filebot (when it is the metadata backend),
+    ffprobe, eject, mountpoint and HandBrakeCLI (when transcode.preset is set)
+  - when storage_path is a MergerFS pool: the pool is mounted and every disk is present
+    and has room for a rip
+
+Each check prints OK, WARN or FAIL. The command exits with an error if any check fails.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	Annotations:  map[string]string{allowInvalidConfig: "true"},
+	RunE:         runConfigValidate,
+}
+
+// runConfigShow prints every key of the effective configuration with its source.
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	fmt.Printf("Config file: %s\n", getConfigPath())
+	if profile := AppConfig.Profile(); profile != "" {
+		fmt.Printf("Profile: %s\n", profile)
+	}
+	if len(AppConfig.Profiles) > 0 {
+		fmt.Printf("Profiles: %s\n", AppConfig.profileNames())
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, key := range configKeys() {
+		value, err := AppConfig.Get(key)
+		if err != nil {
+			return err
+		}
+		switch {
+		case key == "metadata.tmdb_api_key" && value != "":
+			value = "(set)"
+		case value == "":
+			value = `""`
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", key, value, AppConfig.Source(key))
+	}
+	return w.Flush()
+}
+
+// runConfigGet prints the effective value of one key.
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	value, err := AppConfig.Get(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
+
+// runConfigSet changes one key in the config file, refusing values that make the configuration invalid.
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	key, value := args[0], args[1]
+	if key == "version" {
+		return fmt.Errorf("version is the schema version of the file and is not set by hand")
+	}
+
+	// Parse the value the way the config file would be, to check its type and write it in the file's format
+	parsed := defaultConfig()
+	if err := parsed.Set(key, value); err != nil {
+		return err
+	}
+	text, err := parsed.Get(key)
+	if err != nil {
+		return err
+	}
+	quoted := mustField(parsed, key).Kind() == reflect.String
+
+	configPath := getConfigPath()
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+	updated, err := setConfigValue(content, key, text, quoted)
+	if err != nil {
+		return fmt.Errorf("error updating %s: %v", configPath, err)
+	}
+
+	// Refuse the change if it adds problems; problems the file already had are only reported,
+	// so they can be fixed one key at a time
+	config, err := decodeConfig(updated)
+	if err != nil {
+		return fmt.Errorf("%s would not be valid: %v", key, err)
+	}
+	var before []string
+	if old, err := decodeConfig(content); err == nil {
+		before = configProblems(old)
+	}
+	var added, remaining []string
+	for _, p := range configProblems(config) {
+		if slices.Contains(before, p) {
+			remaining = append(remaining, p)
+		} else {
+			added = append(added, p)
+		}
+	}
+	if len(added) > 0 {
+		return fmt.Errorf("not changing %s: %s", key, strings.Join(added, "; "))
+	}
+
+	if err := os.WriteFile(configPath+".tmp", updated, 0644); err != nil {
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	if err := os.Rename(configPath+".tmp", configPath); err != nil {
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	fmt.Printf("Set %s to %s in %s\n", key, text, configPath)
+	switch AppConfig.Source(key) {
+	case configSourceEnv:
+		fmt.Printf("Note: %s is set in the environment and overrides the file\n", configEnvName(key))
+	case configSourceFlag:
+		fmt.Printf("Note: a flag of this run overrides %s\n", key)
+	}
+	for _, p := range remaining {
+		fmt.Printf("Warning: %s\n", p)
+	}
+	return nil
+}
+
+// runConfigValidate checks the configuration and the system, printing one line per check.
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	failed := 0
+	report := func(status, format string, args ...any) {
+		if status == "FAIL" {
+			failed++
+		}
+		fmt.Printf("%-4s  %s\n", status, fmt.Sprintf(format, args...))
+	}
+
+	configPath := getConfigPath()
+	if configErr != nil {
+		for _, line := range strings.Split(configErr.Error(), "\n") {
+			report("FAIL", "%s", line)
+		}
+		report("WARN", "the remaining checks use the default configuration")
+	} else {
+		report("OK", "config file %s is valid", configPath)
+	}
+	if profile := AppConfig.Profile(); profile != "" {
+		report("OK", "checking profile %s", profile)
+	}
+
+	if err := VerifyStoragePath(AppConfig.StoragePath); err != nil {
+		report("FAIL", "storage_path: %v", err)
+	} else {
+		report("OK", "storage path %s is writable", AppConfig.StoragePath)
+	}
+	if AppConfig.StagingPath != "" {
+		if err := VerifyStoragePath(AppConfig.StagingPath); err != nil {
+			report("FAIL", "staging_path: %v", err)
+		} else {
+			report("OK", "staging path %s is writable", AppConfig.StagingPath)
+		}
+	}
+
+	tools := []struct {
+		name     string
+		required bool
+		use      string
+	}{
+		{"makemkvcon", true, "rips discs"},
+		{"filebot", metadataBackend() == metadataBackendFileBot, "looks up media and episode titles when metadata.backend is filebot"},
+		{"ffprobe", false, "verifies ripped files before they are moved into the library"},
+		{"eject", false, "ejects discs after a rip"},
+		{"mountpoint", false, "checks that the MergerFS pool is mounted"},
+		{"HandBrakeCLI", AppConfig.Transcode.Preset != "", "transcodes rips when transcode.preset is set"},
+	}
+	for _, tool := range tools {
+		path, err := runner.LookPath(tool.name)
+		switch {
+		case err == nil:
+			report("OK", "%s is installed at %s", tool.name, path)
+		case tool.required:
+			report("FAIL", "%s is not installed (it %s)", tool.name, tool.use)
+		default:
+			report("WARN", "%s is not installed (it %s)", tool.name, tool.use)
+		}
+	}
+
+	if disks := GetMergerFSDisks(); len(disks) == 0 {
+		report("OK", "storage path is not a MergerFS pool in /etc/fstab; rips are written to it directly")
+	} else {
+		if isMountpoint(AppConfig.StoragePath) {
+			report("OK", "MergerFS pool %s is mounted", AppConfig.StoragePath)
+		} else {
+			report("FAIL", "MergerFS pool %s is listed in /etc/fstab but not mounted", AppConfig.StoragePath)
+		}
+		for _, disk := range disks {
+			space, err := readBranchSpace(disk)
+			switch {
+			case err != nil:
+				report("FAIL", "%v", err)
+			case space.Free < minBranchFreeSpace:
+				report("WARN", "MergerFS disk %s has only %s free; rips are not placed on it", disk, formatBytes(space.Free))
+			default:
+				report("OK", "MergerFS disk %s has %s free", disk, formatBytes(space.Free))
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	fmt.Println("\nrip is ready to rip.")
+	return nil
+}
+
+// configProblems returns the validation problems of a configuration, one per entry.
+func configProblems(config *Config) []string {
+	err := config.Validate()
+	if err == nil {
+		return nil
+	}
+	return strings.Split(err.Error(), "\n")
+}
+
+// mustField returns the field of a key that Set has already accepted.
+func mustField(config *Config, key string) reflect.Value {
+	v, err := config.field(key)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// setConfigValue sets a dotted key in the content of a config file.
+// A key that is already in the file is changed on its own line, keeping the comments and layout;
+// a missing key or a list is added or replaced by rewriting the file through the YAML encoder,
+// which keeps the comments but not always the layout.
+//
+// Parameters:
+//
+//	content - the config file
+//	key - the dotted key, e.g. "limits.write_jobs"
+//	text - the value as written in the file, e.g. "2", "1h5m" or [["plex-scan"]]
+//	quoted - whether the value is a string, written in double quotes
+//
+// Returns the updated file.
+func setConfigValue(content []byte, key, text string, quoted bool) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	value := &yaml.Node{Kind: yaml.ScalarNode, Value: text}
+	if quoted {
+		value.Style = yaml.DoubleQuotedStyle
+	} else {
+		var parsed yaml.Node
+		if err := yaml.Unmarshal([]byte(text), &parsed); err != nil || len(parsed.Content) == 0 {
+			return nil, fmt.Errorf("could not write %s as YAML: %v", text, err)
+		}
+		value = parsed.Content[0]
+	}
+
+	node := doc.Content[0]
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s is not a section", strings.Join(parts[:i], "."))
+		}
+		child := mappingValue(node, part)
+		if child == nil {
+			// Add the key, with any sections it is in
+			for _, name := range parts[i : len(parts)-1] {
+				section := &yaml.Node{Kind: yaml.MappingNode}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, section)
+				node = section
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: parts[len(parts)-1]}, value)
+			return encodeConfigDoc(&doc)
+		}
+		node = child
+	}
+
+	if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return nil, errors.New(key + " is not a single-line value in the file; edit it by hand")
+	}
+	if !onOneLine(node, node.Line) {
+		// A list written over several lines is replaced as a whole
+		value.HeadComment, value.LineComment, value.FootComment = node.HeadComment, node.LineComment, node.FootComment
+		*node = *value
+		return encodeConfigDoc(&doc)
+	}
+	if quoted {
+		text = strconv.Quote(text)
+	}
+	lines := strings.Split(string(content), "\n")
+	line := lines[node.Line-1]
+	if node.Column-1 > len(line) {
+		return nil, fmt.Errorf("could not find the value of %s on line %d", key, node.Line)
+	}
+	updated := line[:node.Column-1] + text
+	if node.LineComment != "" {
+		updated += " " + node.LineComment
+	}
+	lines[node.Line-1] = updated
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// onOneLine reports whether a YAML value and everything in it is written on the given line,
+// such as a scalar or a flow list like [["plex-scan"]].
+func onOneLine(node *yaml.Node, line int) bool {
+	if node.Line != line || (node.Kind != yaml.ScalarNode && node.Style&yaml.FlowStyle == 0 && len(node.Content) > 0) {
+		return false
+	}
+	for _, child := range node.Content {
+		if !onOneLine(child, line) {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeConfigDoc writes a config file from its YAML nodes, with the indentation rip writes.
+func encodeConfigDoc(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// mappingValue returns the value of a key in a YAML mapping node, or nil if the key is not there.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// init registers the config command and its subcommands with the root command.
+func init() {
+	configCmd.AddCommand(configShowCmd, configGetCmd, configSetCmd, configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
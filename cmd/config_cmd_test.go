@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetConfigValue(t *testing.T) {
+	const file = `version: 1
+# Where rips go
+storage_path: /plex/storage # the pool
+limits:
+  # Rips writing at once
+  write_jobs: 1 # one at a time
+  metadata_jobs: 2
+naming:
+  movie_file: |
+    {{.Title}}
+hooks:
+  post_rip:
+    - ["plex-scan", "{dir}"]
+`
+	tests := []struct {
+		name   string
+		key    string
+		text   string
+		quoted bool
+		want   string // The changed lines, or part of the error
+		value  string // The key's value read back with Get
+	}{
+		{"top-level string keeps the comments", "storage_path", "/mnt/media", true,
+			`storage_path: "/mnt/media" # the pool`, "/mnt/media"},
+		{"nested key changed on its line", "limits.write_jobs", "2", false,
+			"  # Rips writing at once\n  write_jobs: 2 # one at a time\n  metadata_jobs: 2\n", "2"},
+		{"missing key in an existing section", "naming.preset", "jellyfin", true,
+			"  movie_file: |\n    {{.Title}}\n  preset: \"jellyfin\"\nhooks:", "jellyfin"},
+		{"missing key in a missing section", "movie.min_length", "45m", false,
+			"    - [\"plex-scan\", \"{dir}\"]\nmovie:\n  min_length: 45m\n", "45m"},
+		{"multi-line list replaced as a whole", "hooks.post_rip", `[["notify", "{title}"]]`, false,
+			"hooks:\n  post_rip: [[\"notify\", \"{title}\"]]\n", `[["notify","{title}"]]`},
+		{"multi-line string", "naming.movie_file", "{{.Title}} ({{.Year}})", true,
+			"naming.movie_file is not a single-line value in the file; edit it by hand", ""},
+		{"key below a value", "storage_path.disk", "1", false, "storage_path is not a section", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := setConfigValue([]byte(file), tt.key, tt.text, tt.quoted)
+			got := string(out)
+			if err != nil {
+				got = err.Error()
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("setConfigValue(%s, %s) =\n%s\nwant it to contain\n%s", tt.key, tt.text, got, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			// The rest of the file and its comments are kept
+			for _, keep := range []string{"version: 1\n", "# Where rips go\n", "# Rips writing at once\n"} {
+				if !strings.Contains(got, keep) {
+					t.Errorf("setConfigValue lost %q:\n%s", keep, got)
+				}
+			}
+			config, err := decodeConfig(out)
+			if err != nil {
+				t.Fatalf("updated file does not decode: %v\n%s", err, got)
+			}
+			if value, err := config.Get(tt.key); err != nil || value != tt.value {
+				t.Errorf("%s = %q (%v) after the change, want %q", tt.key, value, err, tt.value)
+			}
+		})
+	}
+}
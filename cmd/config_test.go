@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseMergerFSBranches(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"disk1", "disk2", "disk10", "parity1", "my disk"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "disk.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := func(name string) string { return filepath.Join(root, name) }
+
+	tests := []struct {
+		name string
+		list string
+		want []string
+	}{
+		{"plain paths", p("disk1") + ":" + p("disk2"), []string{p("disk1"), p("disk2")}},
+		{"glob", p("disk*"), []string{p("disk1"), p("disk10"), p("disk2")}},
+		{"glob and path", p("disk1") + ":" + p("disk*"), []string{p("disk1"), p("disk10"), p("disk2")}},
+		{"glob without matches", p("cache*"), nil},
+		{"read-write mode", p("disk1") + "=RW:" + p("disk2") + "=rw", []string{p("disk1"), p("disk2")}},
+		{"read-only and no-create modes", p("disk1") + "=RW:" + p("disk2") + "=RO:" + p("parity1") + "=NC", []string{p("disk1")}},
+		{"glob with mode", p("disk*") + "=NC:" + p("parity1"), []string{p("parity1")}},
+		{"mode with minimum free space", p("disk1") + "=RW,100G:" + p("disk2") + "=NC,1G", []string{p("disk1")}},
+		{"escaped space", filepath.Join(root, `my\040disk`), []string{p("my disk")}},
+		{"empty entries", ":" + p("disk1") + "::", []string{p("disk1")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMergerFSBranches(tt.list); !slices.Equal(got, tt.want) {
+				t.Errorf("parseMergerFSBranches(%q) = %q, want %q", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLegacyConfig(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	config, err := parseLegacyConfig("# rip configuration file\n\nstorage_path=~/media\n")
+	if err != nil {
+		t.Fatalf("parseLegacyConfig: %v", err)
+	}
+	if want := filepath.Join(home, "media"); config.StoragePath != want {
+		t.Errorf("storage_path = %q, want %q", config.StoragePath, want)
+	}
+	if want := defaultConfig().Metadata; config.Metadata != want {
+		t.Errorf("metadata = %+v, want the defaults %+v", config.Metadata, want)
+	}
+
+	// storage_path was the only key ~/.rip.conf ever had
+	for _, line := range []string{"staging_path=/tmp", "placement_policy=lus", "metadata_backend=tmdb", "max_write_jobs=2", "storage_path"} {
+		if _, err := parseLegacyConfig("storage_path=/plex/storage\n" + line + "\n"); err == nil {
+			t.Errorf("parseLegacyConfig accepted %q", line)
+		}
+	}
+}
+
+// loadTestConfig writes content as the config file and loads it with the given RIP_* environment
+// variables and --storage, --set and --profile flags.
+func loadTestConfig(t *testing.T, content string, env map[string]string, storage string, set []string, profile string) (*Config, error) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, v := range os.Environ() {
+		if name, _, _ := strings.Cut(v, "="); strings.HasPrefix(name, configEnvPrefix) {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+	path := filepath.Join(home, "rip.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(configPathEnv, path)
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+	t.Cleanup(func() { storageFlag, setFlags, profileFlag = "", nil, "" })
+	storageFlag, setFlags, profileFlag = storage, set, profile
+	return LoadConfig()
+}
+
+func TestLoadConfigOverrides(t *testing.T) {
+	const file = "version: 1\nstorage_path: /srv/file\nlimits:\n  write_jobs: 1\nmovie:\n  min_length: 50m\n"
+	tests := []struct {
+		name    string
+		env     map[string]string
+		storage string
+		set     []string
+		key     string
+		want    string
+		source  string
+	}{
+		{"file over the default", nil, "", nil, "limits.write_jobs", "1", configSourceFile},
+		{"default", nil, "", nil, "limits.metadata_jobs", "1", configSourceDefault},
+		{"env over the file", map[string]string{"RIP_LIMITS_WRITE_JOBS": "3"}, "", nil, "limits.write_jobs", "3", configSourceEnv},
+		{"env over the default", map[string]string{"RIP_TV_MIN_EPISODE_LENGTH": "5m"}, "", nil, "tv.min_episode_length", "5m", configSourceEnv},
+		{"--set over env", map[string]string{"RIP_MOVIE_MIN_LENGTH": "40m"}, "", []string{"movie.min_length=30m"}, "movie.min_length", "30m", configSourceFlag},
+		{"--storage over env", map[string]string{"RIP_STORAGE_PATH": "/srv/env"}, "/srv/flag", nil, "storage_path", "/srv/flag", configSourceFlag},
+		{"last --set wins", nil, "", []string{"limits.write_jobs=2", "limits.write_jobs=4"}, "limits.write_jobs", "4", configSourceFlag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadTestConfig(t, file, tt.env, tt.storage, tt.set, "")
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if got, _ := config.Get(tt.key); got != tt.want || config.Source(tt.key) != tt.source {
+				t.Errorf("%s = %q from %s, want %q from %s", tt.key, got, config.Source(tt.key), tt.want, tt.source)
+			}
+		})
+	}
+}
+
+func TestLoadConfigOverrideErrors(t *testing.T) {
+	const file = "version: 1\nstorage_path: /srv/file\n"
+	tests := []struct {
+		name string
+		env  map[string]string
+		set  []string
+		want string
+	}{
+		{"env of the wrong type", map[string]string{"RIP_LIMITS_WRITE_JOBS": "two"}, nil, "RIP_LIMITS_WRITE_JOBS"},
+		{"--set without a value", nil, []string{"limits.write_jobs"}, `--set "limits.write_jobs": expected key=value`},
+		{"--set of an unknown key", nil, []string{"limits.rip_jobs=2"}, `--set "limits.rip_jobs=2"`},
+		{"--set version", nil, []string{"version=2"}, "cannot be overridden"},
+		{"invalid value", nil, []string{"limits.write_jobs=-1"}, "with the RIP_* and flag overrides"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTestConfig(t, file, tt.env, "", tt.set, "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfig error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
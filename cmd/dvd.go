@@ -2,16 +2,14 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"unicode"
 
-	"github.com/rmasci/script"
 	"github.com/spf13/cobra"
 )
 
@@ -25,26 +23,128 @@ var dvdCmd = &cobra.Command{
 	Long: `The "dvd" command automates the ripping of DVDs using MakeMKV,
 categorizing them for use with media libraries like Plex. It requires
 you to provide a physical device path, a category, and optionally a movie name.`,
-	Args: cobra.NoArgs, // No non-flag arguments are required
-	Run:  dvdrip,
+	Args:         cobra.NoArgs, // No non-flag arguments are required
+	RunE:         dvdrip,
+	SilenceUsage: true,
 }
 
 // dvdrip executes the DVD ripping workflow.
 // It performs the following steps:
-// 1. Discovers or accepts the movie name from the DVD or user input
-// 2. Uses FileBot to look up the correct movie name and year (with fallback to user input)
-// 3. Validates the MergerFS mountpoint
-// 4. Fetches metadata from TheMovieDB via FileBot
-// 5. Creates the output directory structure with CamelCase naming
-// 6. Executes MakeMKV to rip the longest title
-// 7. Renames the movie file using FileBot
-// 8. Ejects the disc
-// 9. Displays completion summary
-func dvdrip(cmd *cobra.Command, args []string) {
+// 1. Validates the category and storage path
+// 2. Reads the disc structure with MakeMKV
+// 3. Discovers or accepts the movie name from the DVD or user input
+// 4. Looks up the correct movie name and year in TMDB (with fallback to user input)
+// 5. Works out the output directory and creates a staging directory for the rip
+// 6. Executes MakeMKV to rip the chosen title (see titles.go), or every edition (see editions.go), into the staging directory
+// 7. Names the movie files with the naming templates (see naming.go)
+// 8. Transcodes the movie when transcode.preset is set
+// 9. Verifies the staged files and moves them into the output directory
+// 10. Ejects the disc and runs the post-rip hooks
+// 11. Displays completion summary
+//
+// Errors are returned rather than exiting, so a failed or cancelled (Ctrl-C, SIGTERM) rip can
+// remove what it created and, with --eject-on-failure, eject the disc.
+func dvdrip(cmd *cobra.Command, args []string) (err error) {
 	// Parse command-line flags
-	device, _ := cmd.Flags().GetString("device")
 	category, _ := cmd.Flags().GetString("category")
 	movie, _ := cmd.Flags().GetString("movie")
+	progressMode, _ := cmd.Flags().GetString("progress")
+	ejectOnFailure, _ := cmd.Flags().GetBool("eject-on-failure")
+	strategy, _ := cmd.Flags().GetString("title-strategy")
+	titleID, _ := cmd.Flags().GetInt("title")
+	allEditions, _ := cmd.Flags().GetBool("editions")
+	if allEditions && (titleID >= 0 || cmd.Flags().Changed("title-strategy")) {
+		return fmt.Errorf("--editions rips every cut of the movie, so it cannot be used with --title or --title-strategy")
+	}
+	if strategy == "" {
+		strategy = AppConfig.Movie.TitleStrategy
+	}
+	if !isTitleStrategy(strategy) {
+		return fmt.Errorf("unknown --title-strategy %q (use %s)", strategy, strings.Join(titleStrategies, ", "))
+	}
+
+	progress, err := newProgressListener(progressMode)
+	if err != nil {
+		return err
+	}
+
+	// Validate that category flag was provided
+	if category == "" {
+		return fmt.Errorf("target category must be provided with -c")
+	}
+	// The category becomes a folder name, so it follows the same rules as generated names
+	if clean := AppConfig.Paths.cleanName(category, 0); clean != category {
+		fmt.Printf("Using category %q (%q is not a safe folder name)\n", clean, category)
+		category = clean
+	}
+
+	// Step 1: Verify storage path is accessible before proceeding
+	if err := VerifyStoragePath(AppConfig.StoragePath); err != nil {
+		return fmt.Errorf("%v\n\nPlease edit %s to set a valid storage_path", err, getConfigPath())
+	}
+
+	// Resolve the source (drive, ISO image or disc folder) to a MakeMKV source spec
+	source, err := sourceFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	drive := source.Spec
+	fmt.Printf("Using source: %s\n", source)
+	fmt.Printf("MakeMKV format: %s\n", drive)
+
+	// Lock the drive so no other rip can use it while this one runs
+	var job *Job
+	if source.IsDevice() {
+		title := movie
+		if title == "" && len(args) > 0 {
+			title = args[0]
+		}
+		job, err = startJob(source.Device, "dvd", title)
+		if err != nil {
+			return err
+		}
+		defer job.Finish()
+	}
+
+	// Ctrl-C and SIGTERM cancel the rip; whatever happens, clean up and save the run on the way out
+	ctx, stop := signalContext()
+	defer stop()
+	rollback := newRipRollback(ctx, source, ejectOnFailure)
+	defer func() { err = rollback.Finish(err) }()
+
+	// Record the run in the history, with the output of every command it runs
+	hist := startRunHistory("dvd", os.Args[1:], source, job)
+	rollback.hist = hist
+
+	// Read the disc structure once; it is used for name discovery and title selection
+	fmt.Println("Querying disc for available titles...")
+	job.SetPhase("reading disc")
+	disc, err := readDiscInfo(drive, AppConfig.Movie.MinLength)
+	if err != nil {
+		return fmt.Errorf("error reading disc: %v", err)
+	}
+	hist.SetDisc(disc)
+
+	// Choose the titles to rip before anything else, so an interactive choice comes first
+	printTitles(disc)
+	var editions []edition
+	if allEditions {
+		editions, err = findEditions(disc)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Found %d edition(s):\n", len(editions))
+		for _, e := range editions {
+			fmt.Printf("  Title %d (%s): %s\n", e.Title.ID, formatDuration(e.Title.Duration), e.describe())
+		}
+	} else {
+		choice, err := selectMovieTitle(disc, strategy, titleID, os.Stdin, os.Stdout)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Selected title %d (%s): %s\n", choice.Title.ID, formatDuration(choice.Title.Duration), choice.Reason)
+		editions = []edition{{Title: choice.Title, Reason: choice.Reason}}
+	}
 
 	// Determine movie name from one of three sources (in priority order):
 	// 1. Explicit -m flag provided by user
@@ -61,83 +161,173 @@ func dvdrip(cmd *cobra.Command, args []string) {
 	} else {
 		// Attempt to discover movie name from DVD
 		fmt.Println("Discovering movie name from DVD...")
-		query = discoverMovieName(device)
+		query = discoverMovieName(disc)
+		if query == "" {
+			// Images and folders are usually named after the movie
+			query = source.Name()
+		}
 		if query == "" {
-			log.Fatal("Error: Could not discover movie name from DVD. Please provide it manually using the -m flag.")
+			return fmt.Errorf("could not discover movie name from DVD. Please provide it manually using the -m flag")
 		}
 		fmt.Printf("Discovered movie name: %s\n", query)
 	}
 
-	// Validate that category flag was provided
-	if category == "" {
-		log.Fatalf("Error: Target category must be provided.\n\nUsage:\n")
+	// Step 2: Try to look up the correct movie name and year
+	if job != nil {
+		job.Info.Title = query
 	}
-
-	// Step 1: Verify storage path is accessible before proceeding
-	if err := VerifyStoragePath(AppConfig.StoragePath); err != nil {
-		log.Fatalf("Error: %v\n\nPlease edit ~/.rip.conf to set a valid storage_path", err)
-	}
-
-	// Step 2: Try to look up the correct movie name and year using FileBot
-	// Format: Movie Name (Year)
+	job.SetPhase("looking up metadata")
 	fmt.Printf("Looking up movie info in TMDB for: %s...\n", query)
-	finalName := fetchMetadata(query, "{n} ({y})")
-	if finalName == "" {
-		// Fallback to user-provided name if FileBot lookup fails
+	match, err := lookupMovie(ctx, query, matchOptionsFromFlags(cmd))
+	if err != nil {
+		return err
+	}
+	hist.SetMatch(query, match)
+	if match == nil {
+		// Fallback to user-provided name if the lookup fails
 		fmt.Printf("Warning: Could not find movie in TMDB, using provided name: %s\n", query)
-		finalName = query
-	} else {
+	}
+	naming := namingData(match, query)
+	naming.Resolution = videoResolution(editions[0].Title)
+
+	// Step 3: Work out the output directory and file names from the naming templates, and stage the rip outside it
+	// Directory format: [StoragePath]/Category/[naming.movie_folder]/
+	// Every edition goes in the same directory, told apart by the edition in naming.movie_file
+	// The directory is only created once the rip has been verified
+	finalName, err := movieFolderName(naming)
+	if err != nil {
+		return err
+	}
+	fileNames := make([]string, len(editions))
+	titles := make([]*TitleInfo, len(editions))
+	for i, e := range editions {
+		data := naming
+		data.Edition = e.Name
+		if fileNames[i], err = movieFileName(data); err != nil {
+			return err
+		}
+		if i > 0 && slices.Contains(fileNames[:i], fileNames[i]) {
+			return fmt.Errorf("naming.movie_file gives every edition the same file name %q; add {{.Edition}} to it", fileNames[i])
+		}
+		titles[i] = e.Title
+	}
+	if match != nil {
 		fmt.Printf("Found: %s\n", finalName)
 	}
-
-	// Step 3: Create output directory structure matching the movie name
-	// Directory format: [StoragePath]/Category/Movie Name (Year)/
-	// Jellyfin prefers the directory name to match the actual movie name
-	outDir := filepath.Join(AppConfig.StoragePath, category, finalName)
-	if err := os.MkdirAll(outDir, 0755); err != nil {
-		log.Fatalf("Error creating output directory: %v", err)
+	outDir, err := safeJoin(AppConfig.StoragePath, category, finalName)
+	if err != nil {
+		return err
 	}
 	fmt.Printf("Putting movie in %s\n", outDir)
 
-	// Step 4: Format device path for MakeMKV (handles both Linux and macOS)
-	drive := formatDriveForMakeMKV(device)
-	fmt.Printf("Using device: %s\n", device)
-	fmt.Printf("MakeMKV format: %s\n", drive)
-
-	fmt.Printf("Target: %s/%s.mkv\n", outDir, finalName)
+	for _, fileName := range fileNames {
+		fmt.Printf("Target: %s/%s.mkv\n", outDir, fileName)
+	}
+	stage, err := newStagingArea("dvd", outDir)
+	if err != nil {
+		return err
+	}
+	rollback.stage = stage
+	fmt.Printf("Staging rip in %s\n", stage.Dir)
 
-	// Step 5: Execute MakeMKV rip operation (rips the longest title)
-	if err := runDVDMakeMKV(drive, outDir); err != nil {
-		fmt.Printf("Error during MakeMKV rip: %v\n", err)
-		fmt.Println("Attempting fallback strategies...")
-		// Could add fallback logic here (Stage 2, 3, etc.)
-		log.Fatalf("MakeMKV extraction failed. Please check your DVD and try again.")
+	// Step 4: Execute MakeMKV rip operation (rips the selected title or every edition), and
+	// name each movie file with naming.movie_file as soon as it is ripped
+	release, err := acquireSlot(ctx, job, slotWrite, AppConfig.Limits.WriteJobs)
+	if err != nil {
+		return err
+	}
+	// Fail now rather than part way through the rip if the movie will not fit
+	if err := stage.CheckSpace(titles, outDir); err != nil {
+		release()
+		return err
+	}
+	job.SetPhase("ripping")
+	for i, e := range editions {
+		existing := stage.Files()
+		if err := runDVDMakeMKV(drive, e.Title.ID, stage.Dir, progress); err != nil {
+			release()
+			fmt.Printf("Error during MakeMKV rip: %v\n", err)
+			return fmt.Errorf("MakeMKV extraction failed (%v). Please check your DVD and try again", err)
+		}
+		hist.AddTitle(e.Title, e.describe())
+
+		// Step 5: Name the movie file with naming.movie_file
+		// A file left with MakeMKV's name must not go into the library, so it stays in staging
+		if err := renameMKVFile(stage.Dir, fileNames[i], existing); err != nil {
+			release()
+			stage.Keep()
+			return fmt.Errorf("could not name the ripped movie: %v\nRename the ripped file to %s.mkv and move it into %s", err, fileNames[i], outDir)
+		}
+	}
+	release()
+
+	// Re-encode the movie when transcode.preset is set; it counts as a write job like the rip
+	if AppConfig.Transcode.Preset != "" {
+		job.SetPhase("transcoding")
+		release, err := acquireSlot(ctx, job, slotWrite, AppConfig.Limits.WriteJobs)
+		if err != nil {
+			return err
+		}
+		err = transcodeFiles(stage.Files())
+		release()
+		if err != nil {
+			return err
+		}
 	}
 
-	// Step 6: Rename movie file with metadata from TheMovieDB
-	fmt.Println("Renaming movie file with proper name from FileBot...")
-	if err := renameMovieWithFileBot(finalName, outDir); err != nil {
-		fmt.Printf("Warning: FileBot rename failed: %v\n", err)
+	// Step 6: Verify the staged movie and move it into the library
+	// Nothing goes into the library once the rip has been cancelled
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	job.SetPhase("moving into library")
+	staged := stage.Files()
+	for _, f := range staged {
+		// MakeMKV wrote more than one file for a title, which renameMKVFile numbered
+		if !slices.Contains(fileNames, strings.TrimSuffix(filepath.Base(f), ".mkv")) {
+			stage.Keep()
+			return fmt.Errorf("%s is not named by naming.movie_file (MakeMKV wrote more than one file for a title)\nPick the right file, name it and move it into %s", filepath.Base(f), outDir)
+		}
+	}
+	if err := stage.Verify(staged); err != nil {
+		return err
+	}
+	files, err := stage.Commit(staged, outDir)
+	if err != nil {
+		return err
 	}
 
 	// Step 7: Eject the disc from the drive (only if rip completed successfully)
-	devicePath := extractDevicePath(drive)
-	if err := ejectDisc(devicePath); err != nil {
-		fmt.Printf("Warning: Could not eject disc: %v\n", err)
+	// Images and folders have nothing to eject
+	if source.IsDevice() {
+		if err := ejectDisc(source.Device); err != nil {
+			fmt.Printf("Warning: Could not eject disc: %v\n", err)
+		}
 	}
 
+	runPostRipHooks("dvd", outDir, finalName)
+
 	// Step 8: Display completion summary
 	fmt.Println("-------------------------------------------------------")
 	fmt.Println("RIP COMPLETE!")
 	fmt.Printf("Files are in: %s\n", outDir)
+	hist.SetOutput(outDir, files)
+	return nil
 }
 
 // init registers the dvd command with the root command and configures its flags.
 func init() {
 	// Define command-line flags
-	dvdCmd.Flags().StringP("device", "d", "/dev/sr0", "Physical device path (e.g. /dev/sr0)")
+	dvdCmd.Flags().StringP("device", "d", "", "Physical device path (e.g. /dev/sr0; default: device from the config)")
+	dvdCmd.Flags().String("source", "", "Rip from an image or folder instead of the device (iso:/path/file.iso, file:/path/VIDEO_TS)")
 	dvdCmd.Flags().StringP("category", "c", "", "Target category folder (e.g. Comedy, Action)")
 	dvdCmd.Flags().StringP("movie", "m", "", "Movie name to bypass discovery and use directly")
+	dvdCmd.Flags().String("progress", "bar", "Rip progress output: bar, log, json or none")
+	dvdCmd.Flags().Bool("eject-on-failure", false, "Eject the disc when the rip fails or is cancelled")
+	dvdCmd.Flags().String("title-strategy", "", "How to choose the movie title: "+strings.Join(titleStrategies, ", ")+" (default: movie.title_strategy)")
+	dvdCmd.Flags().Int("title", -1, "Rip this title number instead of choosing one (see the titles listed when the disc is read)")
+	dvdCmd.Flags().Bool("editions", false, "Rip every cut of the movie on the disc (e.g. theatrical and director's cut), named with their edition")
+	addMatchFlags(dvdCmd)
 
 	// Register the dvd command as a subcommand of the root command
 	rootCmd.AddCommand(dvdCmd)
@@ -148,31 +338,8 @@ func init() {
 func isMountpoint(path string) bool {
 	// Use the mountpoint command with -q (quiet) flag
 	// Returns nil (exit code 0) if path is a mountpoint, error otherwise
-	cmd := exec.Command("mountpoint", "-q", path)
-	return cmd.Run() == nil
-}
-
-// fetchMetadata queries FileBot to retrieve metadata from TheMovieDB database.
-// It uses the provided query string and format string to look up and format movie information.
-//
-// Parameters:
-//
-//	query - the search query (movie name)
-//	format - the FileBot format string for output (e.g., "{n} ({y})")
-//
-// Returns the formatted metadata string or empty string if lookup fails.
-func fetchMetadata(query, format string) string {
-	// Execute FileBot list command to query TheMovieDB
-	p := script.Exec(fmt.Sprintf("filebot -list --db TheMovieDB --q '%s' --format '%s'", query, format)).
-		Spinner("Querying TMDB...", 1)
-	out, err := p.String()
-	if err != nil {
-		log.Printf("Error fetching metadata: %v\n", err)
-		return ""
-	}
-	// Parse the first line of output as the metadata result
-	lines := strings.Split(out, "\n")
-	return strings.TrimSpace(lines[0])
+	_, err := runner.Run("mountpoint", "-q", path)
+	return err == nil
 }
 
 // extractDriveIndex extracts the numeric drive index from a device path.
@@ -204,78 +371,23 @@ func formatDriveForMakeMKV(devicePath string) string {
 	return fmt.Sprintf("disc:%s", driveIndex)
 }
 
-// runDVDMakeMKV executes the MakeMKV command to rip the longest title from a DVD.
-// It first queries the disc to identify all available titles, finds the longest one,
-// then executes the rip operation. Ejection is handled by the caller after all steps complete.
+// runDVDMakeMKV executes the MakeMKV command to rip one title from a DVD.
+// The title is chosen by the caller (see selectMovieTitle). Ejection is handled by the
+// caller after all steps complete.
 //
 // Parameters:
 //
 //	drive - the disc specification (e.g., "disc:0")
+//	titleID - the title to rip
 //	outDir - the output directory where the MKV file will be saved
+//	progress - receives progress events while the title is ripped; may be nil
 //
 // Returns an error if the makemkvcon command fails.
-func runDVDMakeMKV(drive, outDir string) error {
-
-	// Step 1: Query the disc to get information about all available titles
-	// Uses the -r flag for robot mode (machine-readable output)
-	fmt.Println("Querying disc for available titles...")
-	p := script.Exec(fmt.Sprintf("makemkvcon -r info %s", drive)).
-		Spinner("Reading disc...", 1)
-	infoOutput, err := p.String()
-	if err != nil {
-		return fmt.Errorf("error running makemkvcon info: %v", err)
-	}
-
-	// Step 2: Parse the output to identify all titles and find the longest one
-	// TINFO line format: TINFO:title_id,27,duration_in_seconds,"duration_in_ms"
-	re := regexp.MustCompile(`TINFO:(\d+),27,\d+,"(\d+)"`)
-	var longestTitleID string
-	var maxDuration int
-	titleDurations := make(map[string]int)
-
-	for _, line := range strings.Split(infoOutput, "\n") {
-		matches := re.FindStringSubmatch(line)
-		if len(matches) == 3 {
-			titleID := matches[1]
-			duration, _ := strconv.Atoi(matches[2])
-			durationSeconds := duration / 1000 // Convert milliseconds to seconds
-			titleDurations[titleID] = durationSeconds
-
-			// Keep track of the title with the maximum duration
-			if durationSeconds > maxDuration {
-				maxDuration = durationSeconds
-				longestTitleID = titleID
-			}
-		}
-	}
-
-	// Print all found titles for debugging
-	if len(titleDurations) > 0 {
-		fmt.Println("Found titles:")
-		for titleID, duration := range titleDurations {
-			minutes := duration / 60
-			seconds := duration % 60
-			fmt.Printf("  Title %s: %d min %d sec (%d seconds)\n", titleID, minutes, seconds, duration)
-		}
-	}
-
-	// Step 3: Run the mkv rip command with the longest title ID
-	titleID := longestTitleID
-	if titleID == "" {
-		fmt.Println("Warning: Could not determine longest title, using title 0")
-		titleID = "0"
-	} else {
-		minutes := maxDuration / 60
-		seconds := maxDuration % 60
-		fmt.Printf("Selected longest title: %s (%d min %d sec)\n", titleID, minutes, seconds)
-	}
-
-	// Execute makemkvcon mkv command to rip the longest title
-	// --minlength=3600 ensures we only rip titles longer than 1 hour (for movies)
-	fmt.Printf("Starting MakeMKV rip (title %s)...\n", titleID)
-	mkv := script.Exec(fmt.Sprintf("makemkvcon mkv %s %s \"%s\" --minlength=3600", drive, titleID, outDir)).
-		Spinner("Extracting video...", 1)
-	output, err := mkv.String()
+func runDVDMakeMKV(drive string, titleID int, outDir string, progress ProgressListener) error {
+	// Execute makemkvcon mkv command to rip the title
+	// --minlength (movie.min_length, 1 hour by default) ensures we only rip feature-length titles
+	fmt.Printf("Starting MakeMKV rip (title %d)...\n", titleID)
+	output, err := runMakeMKVWithProgress([]string{"mkv", drive, strconv.Itoa(titleID), outDir, minLengthFlag(AppConfig.Movie.MinLength)}, progress)
 	if err != nil {
 		fmt.Printf("MakeMKV error output:\n%s\n", output)
 		return fmt.Errorf("makemkvcon mkv command failed: %v", err)
@@ -285,81 +397,20 @@ func runDVDMakeMKV(drive, outDir string) error {
 	return nil
 }
 
-// discoverMovieName attempts to extract the movie title from the DVD disc metadata using MakeMKV.
-// It queries the disc information and parses the output to extract the disc title.
+// discoverMovieName returns the movie title from the DVD disc metadata read by MakeMKV.
+// The disc title (CINFO:2) is preferred; the volume label is used as a fallback,
+// with underscores replaced by spaces (e.g. "THE_MATRIX" -> "THE MATRIX").
 //
 // Parameters:
 //
-//	devicePath - the device path of the DVD drive (e.g., "/dev/sr0")
+//	disc - the disc information returned by readDiscInfo
 //
 // Returns the discovered movie name or empty string if discovery fails.
-func discoverMovieName(devicePath string) string {
-	// Extract the drive index from the device path to format for MakeMKV
-	driveIndex := extractDriveIndex(devicePath)
-	drive := fmt.Sprintf("disc:%s", driveIndex)
-
-	// Query disc information using makemkvcon with robot mode (-r) output
-	p := script.Exec(fmt.Sprintf("makemkvcon -r info %s", drive)).
-		Spinner("Reading disc title...", 1)
-	out, err := p.String()
-	if err != nil {
-		log.Printf("Error running makemkvcon: %v", err)
-		return ""
-	}
-
-	// Parse the output to extract the movie name
-	// CINFO line format: CINFO:2,0,"movie_title"
-	// where: 2 = disc, 0 = disc title field
-	re := regexp.MustCompile(`(?m)^CINFO:2,0,"(.+)"`)
-	matches := re.FindStringSubmatch(out)
-	if len(matches) > 1 {
-		return matches[1]
+func discoverMovieName(disc *DiscInfo) string {
+	if disc.Name != "" {
+		return disc.Name
 	}
-
-	return ""
-}
-
-// renameMovieWithFileBot uses FileBot to rename a movie file with proper name from TheMovieDB database.
-// It renames files recursively in the output directory using a Plex-compatible naming format.
-//
-// Parameters:
-//
-//	movieName - the movie name (currently unused, kept for potential future use)
-//	outDir - the directory containing the movie file to rename
-//
-// The format string produces names like: "Movie Title (Year)"
-//
-// Returns an error if the FileBot rename command fails.
-func renameMovieWithFileBot(movieName, outDir string) error {
-	// FileBot rename command format for movies
-	// Format string: {n} ({y})
-	// where: n=movie name, y=year
-	renameFormat := "{n} ({y})"
-
-	// Execute FileBot rename command with --action move to actually rename files
-	// Uses TheMovieDB database for metadata lookup
-	fmt.Println("Running FileBot to rename movie file...")
-	cmd := fmt.Sprintf("filebot -rename \"%s\" -r --db TheMovieDB --format '%s' --action move", outDir, renameFormat)
-	fmt.Printf("FileBot command: %s\n", cmd)
-
-	p := script.Exec(cmd).
-		Spinner("Renaming file...", 1)
-	output, err := p.String()
-
-	// Always print the output for debugging
-	if output != "" {
-		fmt.Printf("FileBot output:\n%s\n", output)
-	}
-
-	if err != nil {
-		fmt.Printf("FileBot error: %v\n", err)
-		// Don't return error - FileBot might succeed even if script.Exec returns an error
-		// Check if files were actually renamed
-		return nil
-	}
-	fmt.Println("FileBot renamed to the correct movie name successfully.")
-
-	return nil
+	return strings.TrimSpace(strings.ReplaceAll(disc.VolumeName, "_", " "))
 }
 
 // toCamelCase converts a string to CamelCase with no spaces.
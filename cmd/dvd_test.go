@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ripEnv is a library and config for running a rip command against a FakeRunner.
+type ripEnv struct {
+	Storage string // storage_path of the config
+	Fake    *FakeRunner
+}
+
+// newRipEnv writes a config with a temporary storage path and the FileBot backend, points HOME
+// and the config directory (drive locks, history) at a temporary directory, and installs a
+// FakeRunner with no tools scripted. filebot finds nothing, so rips use the names they are given.
+func newRipEnv(t *testing.T) *ripEnv {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	for _, env := range os.Environ() {
+		if name, _, _ := strings.Cut(env, "="); strings.HasPrefix(name, configEnvPrefix) {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+
+	storage := filepath.Join(home, "media")
+	if err := os.Mkdir(storage, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(home, "rip.yaml")
+	content := fmt.Sprintf("version: %d\nstorage_path: %s\nmetadata:\n  backend: filebot\n", configVersion, storage)
+	if err := os.WriteFile(config, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(configPathEnv, config)
+
+	fake := NewFakeRunner()
+	fake.On("filebot").Return("", nil)
+	t.Cleanup(SetRunner(fake))
+	t.Cleanup(func() { resetFlags(rootCmd) })
+	return &ripEnv{Storage: storage, Fake: fake}
+}
+
+// run runs rip with args, with every flag back at its default first.
+func (e *ripEnv) run(t *testing.T, args ...string) error {
+	t.Helper()
+	resetFlags(rootCmd)
+	rootCmd.SetArgs(args)
+	return rootCmd.Execute()
+}
+
+// resetFlags sets the flags of cmd and its subcommands back to their defaults, since cobra keeps
+// flag values between Execute calls.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			v.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.PersistentFlags().VisitAll(reset)
+	cmd.Flags().VisitAll(reset)
+	for _, c := range cmd.Commands() {
+		resetFlags(c)
+	}
+}
+
+// robotInfo returns makemkvcon -r info output for a disc whose titles have the given lengths
+// ("1:45:00"), numbered from 0.
+func robotInfo(lengths ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "TCOUNT:%d\n", len(lengths))
+	b.WriteString("CINFO:2,0,\"Test Disc\"\n")
+	for i, length := range lengths {
+		fmt.Fprintf(&b, "TINFO:%d,8,0,\"12\"\n", i)
+		fmt.Fprintf(&b, "TINFO:%d,9,0,\"%s\"\n", i, length)
+		fmt.Fprintf(&b, "TINFO:%d,27,0,\"title_t%02d.mkv\"\n", i, i)
+	}
+	return b.String()
+}
+
+// ripTitle returns a Do function for makemkvcon mkv that writes the files MakeMKV would to the
+// output directory: title_tNN.mkv for the title ripped, or for every title in all when the
+// command rips "all".
+func ripTitle(all ...int) func(args []string) error {
+	return func(args []string) error {
+		// -r --progress=-same mkv <drive> <title> <dir> --minlength=N
+		titles := all
+		if args[4] != "all" {
+			id := 0
+			fmt.Sscanf(args[4], "%d", &id)
+			titles = []int{id}
+		}
+		for _, id := range titles {
+			name := filepath.Join(args[5], fmt.Sprintf("title_t%02d.mkv", id))
+			if err := os.WriteFile(name, []byte("matroska"), 0644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// findCall returns the first recorded command starting with name and args, or nil.
+func findCall(f *FakeRunner, name string, args ...string) []string {
+	prefix := append([]string{name}, args...)
+	for _, call := range f.Calls {
+		if matchesPrefix(call, prefix) {
+			return call
+		}
+	}
+	return nil
+}
+
+// treeFiles returns the files under dir, relative to it and sorted, skipping the staging directory.
+func treeFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == stagingDirName {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// assertStagingEmpty fails the test if a rip left anything in the staging directory.
+func assertStagingEmpty(t *testing.T, storage string) {
+	t.Helper()
+	entries, _ := os.ReadDir(filepath.Join(storage, stagingDirName))
+	if len(entries) > 0 {
+		t.Errorf("staging directory not cleaned up: %v", entries)
+	}
+}
+
+func TestDVDRip(t *testing.T) {
+	env := newRipEnv(t)
+	env.Fake.Missing["ffprobe"] = true
+	env.Fake.On("makemkvcon", "-r", "info").Return(robotInfo("0:04:12", "1:45:43", "0:22:31"), nil)
+	env.Fake.On("makemkvcon", "-r", "--progress=-same", "mkv").Do(ripTitle())
+	env.Fake.On("eject").Return("", nil)
+
+	if err := env.run(t, "dvd", "--device", "/dev/sr0", "-c", "Drama", "-m", "The Break-Up", "--progress", "none"); err != nil {
+		t.Fatalf("rip dvd: %v", err)
+	}
+
+	if got, want := findCall(env.Fake, "makemkvcon", "-r", "info"), []string{"makemkvcon", "-r", "info", "disc:0", "--minlength=3600"}; !slices.Equal(got, want) {
+		t.Errorf("info command = %q, want %q", got, want)
+	}
+	mkv := findCall(env.Fake, "makemkvcon", "-r", "--progress=-same", "mkv")
+	if len(mkv) != 8 {
+		t.Fatalf("mkv command = %q, want 8 arguments", mkv)
+	}
+	stageDir := mkv[6]
+	if want := []string{"makemkvcon", "-r", "--progress=-same", "mkv", "disc:0", "1", stageDir, "--minlength=3600"}; !slices.Equal(mkv, want) {
+		t.Errorf("mkv command = %q, want %q", mkv, want)
+	}
+	if !strings.HasPrefix(stageDir, filepath.Join(env.Storage, stagingDirName)+string(filepath.Separator)) {
+		t.Errorf("rip went to %s, want a directory under %s", stageDir, filepath.Join(env.Storage, stagingDirName))
+	}
+	if env.Fake.Called("eject", "/dev/sr0") != 1 {
+		t.Errorf("eject /dev/sr0 called %d times, want once (calls: %q)", env.Fake.Called("eject"), env.Fake.Calls)
+	}
+
+	if got, want := treeFiles(t, env.Storage), []string{"Drama/The Break-Up/The Break-Up.mkv"}; !slices.Equal(got, want) {
+		t.Errorf("library = %q, want %q", got, want)
+	}
+	assertStagingEmpty(t, env.Storage)
+}
+
+func TestDVDRipRollsBackFailedRip(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(f *FakeRunner)
+		args  []string
+		eject int
+	}{
+		{
+			name: "makemkvcon fails part way",
+			setup: func(f *FakeRunner) {
+				f.Missing["ffprobe"] = true
+				f.On("makemkvcon", "-r", "--progress=-same", "mkv").Do(ripTitle()).Return("", errors.New("exit status 1"))
+			},
+		},
+		{
+			name: "ripped file does not verify",
+			setup: func(f *FakeRunner) {
+				f.On("makemkvcon", "-r", "--progress=-same", "mkv").Do(ripTitle())
+				f.On("ffprobe").Return("", errors.New("invalid data found when processing input"))
+			},
+		},
+		{
+			name: "eject on failure",
+			setup: func(f *FakeRunner) {
+				f.Missing["ffprobe"] = true
+				f.On("makemkvcon", "-r", "--progress=-same", "mkv").Return("", errors.New("exit status 1"))
+			},
+			args:  []string{"--eject-on-failure"},
+			eject: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newRipEnv(t)
+			env.Fake.On("makemkvcon", "-r", "info").Return(robotInfo("1:45:43"), nil)
+			env.Fake.On("eject").Return("", nil)
+			tt.setup(env.Fake)
+
+			args := append([]string{"dvd", "--device", "/dev/sr0", "-c", "Drama", "-m", "The Break-Up", "--progress", "none"}, tt.args...)
+			if err := env.run(t, args...); err == nil {
+				t.Fatal("rip dvd succeeded, want an error")
+			}
+			if got := treeFiles(t, env.Storage); len(got) > 0 {
+				t.Errorf("failed rip left files in the library: %q", got)
+			}
+			if _, err := os.Stat(filepath.Join(env.Storage, "Drama")); !os.IsNotExist(err) {
+				t.Errorf("failed rip left the category folder behind (%v)", err)
+			}
+			assertStagingEmpty(t, env.Storage)
+			if got := env.Fake.Called("eject", "/dev/sr0"); got != tt.eject {
+				t.Errorf("eject called %d times, want %d", got, tt.eject)
+			}
+		})
+	}
+}
+
+func TestDVDRipKeepsUnnamedFilesInStaging(t *testing.T) {
+	tests := []struct {
+		name    string
+		lengths []string
+		rip     func(args []string) error
+		args    []string
+		kept    []string // Files left in staging
+		want    string
+	}{
+		{
+			name:    "no file to name",
+			lengths: []string{"1:45:43"},
+			rip:     func([]string) error { return nil },
+			want:    "could not name the ripped movie: no MKV files found",
+		},
+		{
+			name:    "two files for one edition",
+			lengths: []string{"1:57:35", "2:11:02"},
+			rip: func(args []string) error {
+				if args[4] == "1" {
+					return ripTitle(1, 5)(append(slices.Clone(args[:4]), "all", args[5]))
+				}
+				return ripTitle()(args)
+			},
+			args: []string{"--editions"},
+			kept: []string{
+				"Blade Runner {edition-Extended Cut}.mkv",
+				"Blade Runner {edition-Extended Cut}1.mkv",
+				"Blade Runner {edition-Theatrical Cut}.mkv",
+			},
+			want: "Blade Runner {edition-Extended Cut}1.mkv is not named by naming.movie_file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newRipEnv(t)
+			env.Fake.Missing["ffprobe"] = true
+			env.Fake.On("makemkvcon", "-r", "info").Return(robotInfo(tt.lengths...), nil)
+			env.Fake.On("makemkvcon", "-r", "--progress=-same", "mkv").Do(tt.rip)
+			env.Fake.On("eject").Return("", nil)
+
+			args := append([]string{"dvd", "--device", "/dev/sr0", "-c", "Sci-Fi", "-m", "Blade Runner", "--progress", "none"}, tt.args...)
+			err := env.run(t, args...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("rip dvd error = %v, want %q", err, tt.want)
+			}
+			if got := treeFiles(t, env.Storage); len(got) > 0 {
+				t.Errorf("files went into the library: %q", got)
+			}
+			stages, _ := filepath.Glob(filepath.Join(env.Storage, stagingDirName, "dvd-*"))
+			if len(stages) != 1 {
+				t.Fatalf("staging directories = %q, want the rip's kept", stages)
+			}
+			var kept []string
+			for _, f := range listMKVFiles(stages[0]) {
+				kept = append(kept, filepath.Base(f))
+			}
+			if !slices.Equal(kept, tt.kept) {
+				t.Errorf("kept in staging = %q, want %q", kept, tt.kept)
+			}
+			if env.Fake.Called("eject") != 0 {
+				t.Error("disc ejected after a failed rip")
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// editionMinDifference is how much two titles must differ in length to be different cuts of the
+// movie. Titles closer than this (e.g. the same cut with other credits or another language) are
+// ripped once.
+const editionMinDifference = time.Minute
+
+// Edition names given by length when the disc does not name the cuts.
+const (
+	editionTheatrical = "Theatrical Cut" // The shortest cut
+	editionExtended   = "Extended Cut"   // Every longer cut
+	editionAlternate  = "Alternate Cut"  // A cut no longer than a theatrical cut the disc names
+)
+
+// editionPatterns maps words in title names to edition names, checked in order.
+// Names are lowercased and "_", "." and "-" turned into spaces before they are matched.
+var editionPatterns = []struct {
+	pattern *regexp.Regexp
+	name    string
+}{
+	{regexp.MustCompile(`\bdirector'?s? cut\b`), "Director's Cut"},
+	{regexp.MustCompile(`\bfinal cut\b`), "Final Cut"},
+	{regexp.MustCompile(`\btheatrical\b`), editionTheatrical},
+	{regexp.MustCompile(`\bextended\b`), "Extended Edition"},
+	{regexp.MustCompile(`\bunrated\b`), "Unrated"},
+	{regexp.MustCompile(`\buncut\b`), "Uncut"},
+	{regexp.MustCompile(`\bultimate\b`), "Ultimate Edition"},
+	{regexp.MustCompile(`\bspecial edition\b`), "Special Edition"},
+	{regexp.MustCompile(`\binternational\b`), "International Cut"},
+	{regexp.MustCompile(`\bimax\b`), "IMAX"},
+	{regexp.MustCompile(`\bremastered\b`), "Remastered"},
+}
+
+// edition is one cut of the movie found on a disc.
+type edition struct {
+	Title  *TitleInfo
+	Name   string // Edition name for naming templates (e.g. "Director's Cut"); empty for the only cut
+	Reason string // Why the title was ripped and named so
+}
+
+// describe returns the edition name and the reason, e.g. "Theatrical Cut (the shortest cut)".
+func (e edition) describe() string {
+	if e.Name == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s (%s)", e.Name, e.Reason)
+}
+
+// editionFromName returns the edition a title name or comment mentions, or an empty string.
+func editionFromName(t *TitleInfo) string {
+	text := strings.ToLower(t.Name + " " + t.Comment)
+	text = strings.NewReplacer("_", " ", ".", " ", "-", " ", "’", "'").Replace(text)
+	for _, p := range editionPatterns {
+		if p.pattern.MatchString(text) {
+			return p.name
+		}
+	}
+	return ""
+}
+
+// findEditions picks the titles of a movie disc that are different cuts of the movie and names them.
+// Of the titles at least movie.min_length long, decoy and Play All titles are dropped first (see
+// dropDuplicateTitles), then titles within editionMinDifference of a shorter one. Each cut left is
+// named after the edition its title name mentions (see editionPatterns); cuts the disc does not name
+// are told apart by length: the shortest is the theatrical cut, longer ones extended cuts. When the
+// disc names the theatrical cut, unnamed cuts longer than it are extended cuts and the others
+// alternate cuts.
+// Every skipped title is printed with the reason.
+//
+// Parameters:
+//
+//	disc - the disc information returned by readDiscInfo
+//
+// Returns the editions, shortest first, or an error if no title is long enough.
+func findEditions(disc *DiscInfo) ([]edition, error) {
+	candidates, err := movieCandidates(disc)
+	if err != nil {
+		return nil, err
+	}
+	kept, _, _ := dropDuplicateTitles(candidates)
+	slices.SortStableFunc(kept, func(a, b *TitleInfo) int { return int(a.Duration - b.Duration) })
+
+	// A word found in every title name is the disc's name (e.g. "Blade Runner The Final Cut"),
+	// not the name of one cut
+	names := make(map[*TitleInfo]string, len(kept))
+	shared := len(kept) > 1
+	for _, t := range kept {
+		names[t] = editionFromName(t)
+		if names[t] == "" || names[t] != names[kept[0]] {
+			shared = false
+		}
+	}
+	if shared {
+		clear(names)
+	}
+
+	var editions []edition
+	for _, t := range kept {
+		if n := len(editions); n > 0 {
+			prev := editions[n-1]
+			if t.Duration-prev.Title.Duration < editionMinDifference && names[t] == names[prev.Title] {
+				fmt.Printf("Skipping title %d (%s): the same length as title %d\n", t.ID, formatDuration(t.Duration), prev.Title.ID)
+				continue
+			}
+		}
+		e := edition{Title: t, Name: names[t]}
+		if e.Name != "" {
+			e.Reason = "named on the disc"
+		}
+		editions = append(editions, e)
+	}
+
+	// Name the cuts the disc does not, by length
+	if len(editions) == 1 {
+		if editions[0].Name == "" {
+			editions[0].Reason = "the only cut on the disc"
+		}
+		return editions, nil
+	}
+	var theatrical *edition
+	for i := range editions {
+		if editions[i].Name == editionTheatrical {
+			theatrical = &editions[i]
+		}
+	}
+	for i := range editions {
+		e := &editions[i]
+		if e.Name != "" {
+			continue
+		}
+		switch {
+		case theatrical == nil:
+			e.Name = editionTheatrical
+			e.Reason = "the shortest cut"
+			if i > 0 {
+				e.Reason = "the shortest cut the disc does not name"
+			}
+			theatrical = e
+		case e.Title.Duration > theatrical.Title.Duration:
+			e.Name = editionExtended
+			e.Reason = fmt.Sprintf("%s longer than the theatrical cut", shortDuration((e.Title.Duration - theatrical.Title.Duration).Round(time.Minute)))
+		default:
+			// Only cuts after a theatrical cut named on the disc can be shorter than it
+			e.Name = editionAlternate
+			e.Reason = "about as long as the theatrical cut"
+			if d := (theatrical.Title.Duration - e.Title.Duration).Round(time.Minute); d > 0 {
+				e.Reason = fmt.Sprintf("%s shorter than the theatrical cut", shortDuration(d))
+			}
+		}
+	}
+
+	// Two cuts of the same name get their running time added, so each file has its own name
+	count := map[string]int{}
+	for _, e := range editions {
+		count[e.Name]++
+	}
+	for i := range editions {
+		if e := &editions[i]; count[e.Name] > 1 {
+			e.Name = fmt.Sprintf("%s %d min", e.Name, int(e.Title.Duration.Minutes()))
+		}
+	}
+	return editions, nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestFindEditions(t *testing.T) {
+	named := func(t *TitleInfo, name string) *TitleInfo {
+		t.Name = name
+		return t
+	}
+	tests := []struct {
+		name   string
+		titles []*TitleInfo
+		want   []string // "ID Name (Reason)"
+	}{
+		{"single cut", []*TitleInfo{testTitle(0, 117, "", 0), testTitle(1, 4, "", 0)},
+			[]string{"0 the only cut on the disc"}},
+		{"unnamed cuts by length", []*TitleInfo{testTitle(0, 134, "", 0), testTitle(1, 117, "", 0), testTitle(2, 117, "", 0)},
+			[]string{"1 Theatrical Cut (the shortest cut)", "0 Extended Cut (17m longer than the theatrical cut)"}},
+		{"named director's cut", []*TitleInfo{named(testTitle(0, 117, "", 0), "Blade Runner"), named(testTitle(1, 118, "", 0), "Director's Cut")},
+			[]string{"0 Theatrical Cut (the shortest cut)", "1 Director's Cut (named on the disc)"}},
+		{"named theatrical cut with a shorter unnamed cut", []*TitleInfo{
+			testTitle(0, 104, "", 0), named(testTitle(1, 117, "", 0), "Theatrical Cut"), testTitle(2, 134, "", 0)},
+			[]string{"0 Alternate Cut (13m shorter than the theatrical cut)", "1 Theatrical Cut (named on the disc)", "2 Extended Cut (17m longer than the theatrical cut)"}},
+	}
+	AppConfig = defaultConfig()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editions, err := findEditions(&DiscInfo{Titles: tt.titles})
+			if err != nil {
+				t.Fatalf("findEditions: %v", err)
+			}
+			var got []string
+			for _, e := range editions {
+				got = append(got, fmt.Sprintf("%d %s", e.Title.ID, e.describe()))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("findEditions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDVDRipEditions(t *testing.T) {
+	env := newRipEnv(t)
+	env.Fake.Missing["ffprobe"] = true
+	env.Fake.On("makemkvcon", "-r", "info").Return(robotInfo("0:04:12", "1:57:35", "2:11:02"), nil)
+	env.Fake.On("makemkvcon", "-r", "--progress=-same", "mkv").Do(ripTitle())
+	env.Fake.On("eject").Return("", nil)
+
+	if err := env.run(t, "dvd", "--device", "/dev/sr0", "-c", "Sci-Fi", "-m", "Blade Runner", "--editions", "--progress", "none"); err != nil {
+		t.Fatalf("rip dvd --editions: %v", err)
+	}
+
+	// The title IDs come from reading the disc with the same --minlength the rips use, so MakeMKV
+	// numbers the titles the same way both times
+	if got := findCall(env.Fake, "makemkvcon", "-r", "info"); got[len(got)-1] != "--minlength=3600" {
+		t.Errorf("info command = %q, want --minlength=3600", got)
+	}
+	var ripped []string
+	for _, call := range env.Fake.Calls {
+		if matchesPrefix(call, []string{"makemkvcon", "-r", "--progress=-same", "mkv"}) {
+			ripped = append(ripped, call[5]+" "+call[7])
+		}
+	}
+	if want := []string{"1 --minlength=3600", "2 --minlength=3600"}; !slices.Equal(ripped, want) {
+		t.Errorf("ripped titles %q, want %q", ripped, want)
+	}
+
+	want := []string{
+		"Sci-Fi/Blade Runner/Blade Runner {edition-Extended Cut}.mkv",
+		"Sci-Fi/Blade Runner/Blade Runner {edition-Theatrical Cut}.mkv",
+	}
+	if got := treeFiles(t, env.Storage); !slices.Equal(got, want) {
+		t.Errorf("library = %q, want %q", got, want)
+	}
+	assertStagingEmpty(t, env.Storage)
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the `history` command, which lists past rips.
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List past rips",
+	Long: `List past rip dvd and rip tv runs, newest first.
+
+Every run is saved with its source, disc label, selected titles, metadata match, output files,
+result and timings, and the output of every makemkvcon and filebot command it ran.
+Use rip show <job> to see one of them in full.`,
+	Args: cobra.NoArgs,
+	Run:  runHistoryList,
+}
+
+// showCmd represents the `show` command, which prints one past rip.
+var showCmd = &cobra.Command{
+	Use:   "show [job]",
+	Short: "Show a past rip and its command log",
+	Long: `Show a past rip in full, followed by the output of the commands it ran.
+The job can be given as its full ID or any unique prefix of it.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runHistoryShow,
+}
+
+// runHistoryList prints the history records matching the filter flags.
+func runHistoryList(cmd *cobra.Command, args []string) {
+	kind, _ := cmd.Flags().GetString("type")
+	status, _ := cmd.Flags().GetString("status")
+	search, _ := cmd.Flags().GetString("search")
+	since, _ := cmd.Flags().GetString("since")
+	limit, _ := cmd.Flags().GetInt("limit")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	var sinceTime time.Time
+	if since != "" {
+		t, err := parseSince(since)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		sinceTime = t
+	}
+
+	records, err := loadHistory()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	// Newest first, filtered
+	var matches []HistoryRecord
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		if kind != "" && r.Kind != kind {
+			continue
+		}
+		if status != "" && r.Status != status {
+			continue
+		}
+		if !sinceTime.IsZero() && r.StartedAt.Before(sinceTime) {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(historyTitle(r)+" "+r.DiscLabel+" "+r.Query), strings.ToLower(search)) {
+			continue
+		}
+		matches = append(matches, r)
+		if limit > 0 && len(matches) == limit {
+			break
+		}
+	}
+
+	if asJSON {
+		if matches == nil {
+			matches = []HistoryRecord{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(matches); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	if len(matches) == 0 {
+		fmt.Println("No rips found.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tSTARTED\tTYPE\tSTATUS\tTOOK\tTITLE")
+	for _, r := range matches {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, r.StartedAt.Format("2006-01-02 15:04"), r.Kind, r.Status,
+			r.FinishedAt.Sub(r.StartedAt).Round(time.Second), historyTitle(r))
+	}
+	w.Flush()
+}
+
+// runHistoryShow prints one history record and its command log.
+func runHistoryShow(cmd *cobra.Command, args []string) {
+	noLog, _ := cmd.Flags().GetBool("no-log")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	records, err := loadHistory()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	r, err := findHistoryRecord(records, args[0])
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	fmt.Printf("Job:       %s\n", r.ID)
+	fmt.Printf("Command:   %s\n", commandLine("rip", r.Args...))
+	fmt.Printf("Status:    %s\n", r.Status)
+	if r.Error != "" {
+		fmt.Printf("Error:     %s\n", r.Error)
+	}
+	fmt.Printf("Started:   %s\n", r.StartedAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Finished:  %s (took %s)\n", r.FinishedAt.Format("2006-01-02 15:04:05"), r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+	fmt.Printf("Source:    %s\n", r.Source)
+	if r.DiscLabel != "" {
+		fmt.Printf("Disc:      %s\n", r.DiscLabel)
+	}
+	for _, t := range r.Titles {
+		fmt.Printf("Title:     %d (%s", t.ID, t.Duration)
+		if t.Size != "" {
+			fmt.Printf(", %s", t.Size)
+		}
+		fmt.Print(")")
+		if t.Reason != "" {
+			fmt.Printf(": %s", t.Reason)
+		}
+		fmt.Println()
+	}
+	if r.Query != "" {
+		fmt.Printf("Query:     %s\n", r.Query)
+	}
+	if r.Match != nil {
+		fmt.Printf("Match:     %s (%d), TMDB %d\n", r.Match.Title, r.Match.Year, r.Match.ID)
+	} else if r.Query != "" {
+		fmt.Println("Match:     none")
+	}
+	if r.OutputDir != "" {
+		fmt.Printf("Output:    %s\n", r.OutputDir)
+	}
+	for _, f := range r.Files {
+		fmt.Printf("File:      %s\n", f)
+	}
+
+	if noLog || r.LogFile == "" {
+		return
+	}
+	data, err := os.ReadFile(r.LogFile)
+	if err != nil {
+		fmt.Printf("\nCommand log not available: %v\n", err)
+		return
+	}
+	fmt.Printf("\nCommand log (%s):\n", r.LogFile)
+	os.Stdout.Write(data)
+}
+
+// findHistoryRecord returns the record whose ID is id, or the only record whose ID starts with id.
+func findHistoryRecord(records []HistoryRecord, id string) (*HistoryRecord, error) {
+	var found []HistoryRecord
+	for _, r := range records {
+		if r.ID == id {
+			return &r, nil
+		}
+		if strings.HasPrefix(r.ID, id) {
+			found = append(found, r)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no rip %q in the history (see rip history)", id)
+	case 1:
+		return &found[0], nil
+	default:
+		return nil, fmt.Errorf("%q matches %d rips; give more of the job ID", id, len(found))
+	}
+}
+
+// historyTitle returns the title shown for a record: the matched name, else the query, else the disc label.
+func historyTitle(r HistoryRecord) string {
+	switch {
+	case r.Match != nil && r.Match.Year > 0:
+		return fmt.Sprintf("%s (%d)", r.Match.Title, r.Match.Year)
+	case r.Match != nil:
+		return r.Match.Title
+	case r.Query != "":
+		return r.Query
+	}
+	return r.DiscLabel
+}
+
+// parseSince parses the --since flag: a date (2024-01-31) or a number of days or hours ago (7d, 12h).
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if strings.HasSuffix(value, "d") {
+		if days := atoiOrZero(strings.TrimSuffix(value, "d")); days > 0 {
+			return time.Now().AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (use a date like 2024-01-31 or an age like 7d or 12h)", value)
+}
+
+// init registers the history and show commands with the root command and configures their flags.
+func init() {
+	historyCmd.Flags().String("type", "", "Only show rips of this type: dvd or tv")
+	historyCmd.Flags().String("status", "", "Only show rips with this result: success or failed")
+	historyCmd.Flags().String("search", "", "Only show rips whose title, query or disc label contains this text")
+	historyCmd.Flags().String("since", "", "Only show rips started since a date (2024-01-31) or within an age (7d, 12h)")
+	historyCmd.Flags().IntP("limit", "n", 20, "Maximum number of rips to show (0 for all)")
+	historyCmd.Flags().Bool("json", false, "Print the rips as JSON")
+
+	showCmd.Flags().Bool("no-log", false, "Do not print the command log")
+	showCmd.Flags().Bool("json", false, "Print the rip as JSON")
+
+	rootCmd.AddCommand(historyCmd, showCmd)
+}
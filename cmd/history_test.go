@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindHistoryRecord(t *testing.T) {
+	records := []HistoryRecord{
+		{ID: "20240131-201500-sr0"},
+		{ID: "20240131-201500-sr1"},
+		{ID: "20240202-093000-dvd-123"},
+		{ID: "20240202-093000-dvd-1234"},
+	}
+	tests := []struct {
+		id   string
+		want string // ID found, or part of the error
+	}{
+		{"20240131-201500-sr1", "20240131-201500-sr1"},
+		{"20240131-201500-sr", `"20240131-201500-sr" matches 2 rips`},
+		{"20240131", `"20240131" matches 2 rips`},
+		{"20240202-093000-dvd-123", "20240202-093000-dvd-123"}, // Exact match, though also a prefix of another ID
+		{"20240202-093000-dvd-12", `matches 2 rips`},
+		{"20240202-093000-dvd-1234", "20240202-093000-dvd-1234"},
+		{"20240131-2015", `matches 2 rips`},
+		{"2023", `no rip "2023" in the history`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			r, err := findHistoryRecord(records, tt.id)
+			var got string
+			if err != nil {
+				got = err.Error()
+			} else {
+				got = r.ID
+			}
+			if !strings.Contains(got, tt.want) || (err == nil && got != tt.want) {
+				t.Errorf("findHistoryRecord(%q) = %q, want %q", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		value string
+		want  time.Time // Zero for an error
+	}{
+		{"2024-01-31", time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"1d", now.AddDate(0, 0, -1)},
+		{"12h", now.Add(-12 * time.Hour)},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"0d", time.Time{}},
+		{"-3h", time.Time{}},
+		{"d", time.Time{}},
+		{"2024-13-01", time.Time{}},
+		{"yesterday", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSince(tt.value)
+			if tt.want.IsZero() {
+				if err == nil {
+					t.Errorf("parseSince(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSince(%q): %v", tt.value, err)
+			}
+			if diff := got.Sub(tt.want); diff < -time.Minute || diff > time.Minute {
+				t.Errorf("parseSince(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
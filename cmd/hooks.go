@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// runPostRipHooks runs the hooks.post_rip commands once a rip is in the library, for example to
+// start a library scan or send a notification. Each command runs directly, without a shell.
+// These placeholders are replaced in its arguments:
+//
+//	{kind} - dvd or tv
+//	{dir} - the library directory the rip was moved to
+//	{title} - the movie or show name
+//	{profile} - the profile in use (empty without one)
+//
+// A failing hook is reported but does not fail the rip, which is already complete.
+func runPostRipHooks(kind, dir, title string) {
+	replacer := strings.NewReplacer("{kind}", kind, "{dir}", dir, "{title}", title, "{profile}", AppConfig.Profile())
+	for _, command := range AppConfig.Hooks.PostRip {
+		args := make([]string, len(command)-1)
+		for i, arg := range command[1:] {
+			args[i] = replacer.Replace(arg)
+		}
+		fmt.Printf("Running post-rip hook: %s\n", commandLine(command[0], args...))
+		out, err := runner.Run(command[0], args...)
+		if out = strings.TrimSpace(out); out != "" {
+			fmt.Println(out)
+		}
+		if err != nil {
+			fmt.Printf("Warning: post-rip hook %s failed: %v\n", command[0], err)
+		}
+	}
+}
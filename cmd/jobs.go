@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// jobsCmd represents the `jobs` command, which shows the rips currently running on this machine.
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Show which rip job is running on which drive",
+	Long: `Show the rip jobs currently running on this machine and the drive each one is using.
+
+Every rip started from the command line, by rip watch or by rip web locks its drive, so this
+lists all of them together with their current step (reading disc, looking up metadata,
+waiting for a write slot, ripping, renaming).`,
+	Args: cobra.NoArgs,
+	Run:  runJobs,
+}
+
+// runJobs prints the running jobs as a table or as JSON.
+func runJobs(cmd *cobra.Command, args []string) {
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	jobs, err := runningJobs()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	if asJSON {
+		if jobs == nil {
+			jobs = []JobInfo{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(jobs); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	if len(jobs) == 0 {
+		fmt.Println("No rips are running.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DRIVE\tJOB\tTYPE\tTITLE\tPHASE\tRUNNING\tPID")
+	for _, j := range jobs {
+		elapsed := time.Since(j.StartedAt).Round(time.Second)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n", j.Device, j.ID, j.Kind, j.Title, j.Phase, elapsed, j.PID)
+	}
+	w.Flush()
+	fmt.Printf("\nLimits: %s metadata, %s write\n", slotLimit(AppConfig.Limits.MetadataJobs), slotLimit(AppConfig.Limits.WriteJobs))
+}
+
+// slotLimit formats a slot limit for display.
+func slotLimit(limit int) string {
+	if limit <= 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d at a time", limit)
+}
+
+// init registers the jobs command with the root command and configures its flags.
+func init() {
+	jobsCmd.Flags().Bool("json", false, "Print the jobs as JSON")
+	rootCmd.AddCommand(jobsCmd)
+}
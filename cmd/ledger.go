@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ledgerFileName is the name of the disc ledger kept in each "Season XX" directory.
+// The leading dot keeps it out of the way of Plex and Jellyfin.
+const ledgerFileName = ".rip-ledger.json"
+
+// DiscLedger records which episodes each ripped disc of a season produced.
+// rip tv writes it after every disc, uses it to number the episodes of later discs,
+// and rip tv status reads it to show which discs are still missing.
+type DiscLedger struct {
+	Show   string        `json:"show"`   // Show name as used in the episode file names
+	Season int           `json:"season"` // Season number
+	Discs  []*LedgerDisc `json:"discs"`  // Ripped discs, ordered by disc number
+}
+
+// LedgerDisc is one ripped disc in the ledger.
+type LedgerDisc struct {
+	Disc     int            `json:"disc"`     // Disc number within the season
+	Label    string         `json:"label"`    // Disc name or volume label reported by MakeMKV
+	RippedAt time.Time      `json:"rippedAt"` // When the rip finished
+	Titles   []*LedgerTitle `json:"titles"`   // Episode titles ripped from the disc, in episode order
+}
+
+// LedgerTitle is one disc title that was kept as an episode.
+type LedgerTitle struct {
+	TitleID  int    `json:"titleId"`  // MakeMKV title index on the disc
+	Duration string `json:"duration"` // Title duration (e.g. "22m41s")
+	File     string `json:"file"`     // Final episode file name in the season directory
+	Episode  int    `json:"episode"`  // Episode number assigned to the title
+}
+
+// loadLedger reads the disc ledger from a season directory.
+// If the directory has no ledger yet, an empty ledger for show and season is returned.
+func loadLedger(dir, show string, season int) (*DiscLedger, error) {
+	ledger := &DiscLedger{Show: show, Season: season}
+	data, err := os.ReadFile(filepath.Join(dir, ledgerFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading disc ledger: %v", err)
+	}
+	if err := json.Unmarshal(data, ledger); err != nil {
+		return nil, fmt.Errorf("error parsing disc ledger %s: %v", filepath.Join(dir, ledgerFileName), err)
+	}
+	return ledger, nil
+}
+
+// save writes the ledger to the season directory.
+// The file is written under a temporary name first so an interrupted write never leaves a broken ledger.
+func (l *DiscLedger) save(dir string) error {
+	sort.Slice(l.Discs, func(i, j int) bool { return l.Discs[i].Disc < l.Discs[j].Disc })
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding disc ledger: %v", err)
+	}
+	path := filepath.Join(dir, ledgerFileName)
+	if err := os.WriteFile(path+".tmp", append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing disc ledger: %v", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("error writing disc ledger: %v", err)
+	}
+	return nil
+}
+
+// Disc returns the ledger entry for a disc number, or nil if that disc has not been ripped.
+func (l *DiscLedger) Disc(number int) *LedgerDisc {
+	for _, d := range l.Discs {
+		if d.Disc == number {
+			return d
+		}
+	}
+	return nil
+}
+
+// record adds a disc to the ledger, replacing any earlier entry for the same disc number.
+func (l *DiscLedger) record(disc *LedgerDisc) {
+	for i, d := range l.Discs {
+		if d.Disc == disc.Disc {
+			l.Discs[i] = disc
+			return
+		}
+	}
+	l.Discs = append(l.Discs, disc)
+}
+
+// NextEpisode returns the first episode number of a disc, based on the discs before it.
+// It returns 0 if the ledger has no earlier discs, so the caller can fall back to other sources.
+func (l *DiscLedger) NextEpisode(disc int) int {
+	highest := 0
+	found := false
+	for _, d := range l.Discs {
+		if d.Disc >= disc {
+			continue
+		}
+		found = true
+		for _, t := range d.Titles {
+			if t.Episode > highest {
+				highest = t.Episode
+			}
+		}
+	}
+	if !found {
+		return 0
+	}
+	return highest + 1
+}
+
+// MissingDiscs returns the disc numbers not in the ledger, from 1 up to the highest ripped disc
+// or to total, whichever is larger.
+func (l *DiscLedger) MissingDiscs(total int) []int {
+	last := total
+	for _, d := range l.Discs {
+		if d.Disc > last {
+			last = d.Disc
+		}
+	}
+	var missing []int
+	for n := 1; n <= last; n++ {
+		if l.Disc(n) == nil {
+			missing = append(missing, n)
+		}
+	}
+	return missing
+}
+
+// discLabel returns the label recorded for a disc: its name, or its volume label if it has no name.
+func discLabel(disc *DiscInfo) string {
+	if disc.Name != "" {
+		return disc.Name
+	}
+	return disc.VolumeName
+}
+
+// ledgerTitles builds the ledger titles for the episodes ripped from a disc.
+// MakeMKV names each output file after its title (TINFO attribute 27), which is how files are
+// matched back to title IDs and durations. The final file name is looked up by the SxxEyy
+// episode number in the season directory, since numberEpisodes names the files with
+// naming.episode_file and only that part of the name is fixed.
+//
+// Parameters:
+//
+//	disc - the disc structure read before ripping
+//	ripped - the original MakeMKV file names of the kept episodes, in episode order
+//	dir - the season directory holding the final episode files
+//	season - the season number
+//	startEpisode - the episode number of the first ripped file
+//
+// Returns the ledger titles in episode order.
+func ledgerTitles(disc *DiscInfo, ripped []string, dir string, season, startEpisode int) []*LedgerTitle {
+	byFile := make(map[string]*TitleInfo)
+	for _, t := range disc.Titles {
+		if t.OutputFileName != "" {
+			byFile[t.OutputFileName] = t
+		}
+	}
+
+	byEpisode := make(map[int]string)
+	for _, f := range listMKVFiles(dir) {
+		m := episodeNumberPattern.FindStringSubmatch(filepath.Base(f))
+		if m == nil {
+			continue
+		}
+		if s := atoiOrZero(m[1]); s == season {
+			byEpisode[atoiOrZero(m[2])] = filepath.Base(f)
+		}
+	}
+
+	var titles []*LedgerTitle
+	for i, f := range ripped {
+		entry := &LedgerTitle{TitleID: -1, Episode: startEpisode + i, File: byEpisode[startEpisode+i]}
+		if t, ok := byFile[filepath.Base(f)]; ok {
+			entry.TitleID = t.ID
+			entry.Duration = t.Duration.String()
+		}
+		titles = append(titles, entry)
+	}
+	return titles
+}
@@ -0,0 +1,548 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Attribute IDs used in makemkvcon robot-mode CINFO, TINFO and SINFO lines.
+// The values come from MakeMKV's apdefs.h (enum AP_ItemAttributeId).
+const (
+	attrType             = 1
+	attrName             = 2
+	attrLangCode         = 3
+	attrLangName         = 4
+	attrCodecID          = 5
+	attrCodecShort       = 6
+	attrCodecLong        = 7
+	attrChapterCount     = 8
+	attrDuration         = 9
+	attrDiskSize         = 10
+	attrDiskSizeBytes    = 11
+	attrBitrate          = 13
+	attrAudioChannels    = 14
+	attrAngleInfo        = 15
+	attrSourceFileName   = 16
+	attrAudioSampleRate  = 17
+	attrVideoSize        = 19
+	attrVideoAspectRatio = 20
+	attrVideoFrameRate   = 21
+	attrSegmentsCount    = 25
+	attrSegmentsMap      = 26
+	attrOutputFileName   = 27
+	attrMetadataLangCode = 28
+	attrMetadataLangName = 29
+	attrTreeInfo         = 30
+	attrVolumeName       = 32
+	attrComment          = 49
+)
+
+// DiscInfo is the typed model of a disc built from `makemkvcon -r info` output.
+type DiscInfo struct {
+	Type         string         // Disc type (e.g. "DVD disc", "Blu-ray disc")
+	Name         string         // Disc title (CINFO:2)
+	VolumeName   string         // Volume label (CINFO:32)
+	Language     string         // Metadata language code (CINFO:28)
+	LanguageName string         // Metadata language name (CINFO:29)
+	TitleCount   int            // Number of titles reported by TCOUNT
+	Titles       []*TitleInfo   // Titles in the order makemkvcon reported them
+	Drives       []DriveInfo    // Drives reported by DRV lines
+	Messages     []RobotMessage // MSG lines, in order
+	Attributes   map[int]string // All CINFO attributes keyed by attribute ID
+}
+
+// TitleInfo describes a single title on the disc (TINFO lines).
+type TitleInfo struct {
+	ID             int            // Title index used by `makemkvcon mkv`
+	Name           string         // Title name, if the disc provides one
+	Chapters       int            // Number of chapters
+	Duration       time.Duration  // Running time
+	Size           string         // Human readable size (e.g. "4.3 GB")
+	SizeBytes      int64          // Size in bytes
+	SourceFileName string         // Source playlist or IFO (e.g. "00800.mpls")
+	SegmentCount   int            // Number of segments in the title
+	SegmentMap     string         // Comma separated segment list (e.g. "1,2,3")
+	OutputFileName string         // File name makemkvcon will write (e.g. "title_t00.mkv")
+	Language       string         // Metadata language code
+	Comment        string         // Comment, if any
+	Streams        []*StreamInfo  // Video, audio and subtitle streams
+	Attributes     map[int]string // All TINFO attributes keyed by attribute ID
+}
+
+// StreamInfo describes a single stream within a title (SINFO lines).
+type StreamInfo struct {
+	ID           int            // Stream index within the title
+	Type         string         // "Video", "Audio" or "Subtitles"
+	Codec        string         // Short codec name (e.g. "Mpeg2", "AC3")
+	CodecLong    string         // Long codec name
+	Language     string         // Language code (e.g. "eng")
+	LanguageName string         // Language name (e.g. "English")
+	Bitrate      string         // Bitrate as reported (e.g. "448 Kb/s")
+	Channels     int            // Audio channel count
+	SampleRate   int            // Audio sample rate in Hz
+	VideoSize    string         // Video resolution (e.g. "720x480")
+	AspectRatio  string         // Video aspect ratio (e.g. "16:9")
+	FrameRate    string         // Video frame rate
+	Angle        string         // Angle information
+	Attributes   map[int]string // All SINFO attributes keyed by attribute ID
+}
+
+// DriveInfo describes a drive reported by a DRV line.
+type DriveInfo struct {
+	Index     int    // Drive index used in disc:N
+	State     int    // Drive state (see driveState* constants)
+	Flags     int    // Drive flags
+	DriveName string // Drive model (e.g. "BD-RE HL-DT-ST BD-RE WH16NS40")
+	DiscName  string // Label of the inserted disc, empty when there is none
+	Device    string // Device path (e.g. "/dev/sr0")
+}
+
+// Drive states reported in DRV lines (from MakeMKV's apdefs.h).
+const (
+	driveStateEmptyClosed = 0
+	driveStateEmptyOpen   = 1
+	driveStateInserted    = 2
+	driveStateLoading     = 3
+	driveStateNoDrive     = 256
+)
+
+// HasDisc reports whether the drive currently holds a readable disc.
+func (d DriveInfo) HasDisc() bool {
+	return d.State == driveStateInserted
+}
+
+// RobotMessage is an informational MSG line from makemkvcon.
+type RobotMessage struct {
+	Code   int      // Message code
+	Flags  int      // Message flags
+	Text   string   // Formatted message text
+	Format string   // Unformatted message format string
+	Params []string // Format parameters
+}
+
+// MakeMKVParser turns makemkvcon robot-mode output (-r) into a DiscInfo.
+// Lines are fed one at a time with ParseLine so output can be parsed while it is streaming.
+type MakeMKVParser struct {
+	disc   *DiscInfo
+	titles map[int]*TitleInfo
+}
+
+// NewMakeMKVParser returns a parser with an empty disc model.
+func NewMakeMKVParser() *MakeMKVParser {
+	return &MakeMKVParser{
+		disc:   &DiscInfo{Attributes: map[int]string{}},
+		titles: map[int]*TitleInfo{},
+	}
+}
+
+// ParseLine parses a single robot-mode line and adds it to the disc model.
+// Unknown line types (including progress lines) are ignored.
+//
+// Returns an error if a known line type has malformed fields.
+func (p *MakeMKVParser) ParseLine(line string) error {
+	kind, fields, ok := splitRobotLine(line)
+	if !ok {
+		return nil
+	}
+
+	switch kind {
+	case "CINFO":
+		// CINFO:attribute_id,code,"value"
+		if len(fields) < 3 {
+			return fmt.Errorf("malformed CINFO line: %s", line)
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("malformed CINFO line: %s", line)
+		}
+		p.setDiscAttribute(id, fields[2])
+
+	case "TINFO":
+		// TINFO:title_id,attribute_id,code,"value"
+		if len(fields) < 4 {
+			return fmt.Errorf("malformed TINFO line: %s", line)
+		}
+		titleID, err1 := strconv.Atoi(fields[0])
+		id, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("malformed TINFO line: %s", line)
+		}
+		p.title(titleID).setAttribute(id, fields[3])
+
+	case "SINFO":
+		// SINFO:title_id,stream_id,attribute_id,code,"value"
+		if len(fields) < 5 {
+			return fmt.Errorf("malformed SINFO line: %s", line)
+		}
+		titleID, err1 := strconv.Atoi(fields[0])
+		streamID, err2 := strconv.Atoi(fields[1])
+		id, err3 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil || err3 != nil {
+			return fmt.Errorf("malformed SINFO line: %s", line)
+		}
+		p.title(titleID).stream(streamID).setAttribute(id, fields[4])
+
+	case "TCOUNT":
+		// TCOUNT:title_count
+		if len(fields) < 1 {
+			return fmt.Errorf("malformed TCOUNT line: %s", line)
+		}
+		count, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("malformed TCOUNT line: %s", line)
+		}
+		p.disc.TitleCount = count
+
+	case "DRV":
+		// DRV:index,state,unused,flags,"drive name","disc name","device path"
+		if len(fields) < 6 {
+			return fmt.Errorf("malformed DRV line: %s", line)
+		}
+		drive := DriveInfo{
+			Index:     atoiOrZero(fields[0]),
+			State:     atoiOrZero(fields[1]),
+			Flags:     atoiOrZero(fields[3]),
+			DriveName: fields[4],
+			DiscName:  fields[5],
+		}
+		if len(fields) > 6 {
+			drive.Device = fields[6]
+		}
+		// makemkvcon pads the drive list with empty slots; only keep real drives
+		if drive.State != driveStateNoDrive && drive.DriveName != "" {
+			p.disc.Drives = append(p.disc.Drives, drive)
+		}
+
+	case "MSG":
+		// MSG:code,flags,count,"message","format","param0","param1",...
+		if len(fields) < 5 {
+			return fmt.Errorf("malformed MSG line: %s", line)
+		}
+		p.disc.Messages = append(p.disc.Messages, RobotMessage{
+			Code:   atoiOrZero(fields[0]),
+			Flags:  atoiOrZero(fields[1]),
+			Text:   fields[3],
+			Format: fields[4],
+			Params: fields[5:],
+		})
+	}
+
+	return nil
+}
+
+// Disc returns the disc model built from the lines parsed so far.
+// Titles are returned in title ID order.
+func (p *MakeMKVParser) Disc() *DiscInfo {
+	return p.disc
+}
+
+// title returns the title with the given ID, creating it if needed.
+func (p *MakeMKVParser) title(id int) *TitleInfo {
+	if t, ok := p.titles[id]; ok {
+		return t
+	}
+	t := &TitleInfo{ID: id, Attributes: map[int]string{}}
+	p.titles[id] = t
+
+	// Keep titles sorted by ID; makemkvcon reports them in order so this is usually an append
+	i := len(p.disc.Titles)
+	for i > 0 && p.disc.Titles[i-1].ID > id {
+		i--
+	}
+	p.disc.Titles = append(p.disc.Titles, nil)
+	copy(p.disc.Titles[i+1:], p.disc.Titles[i:])
+	p.disc.Titles[i] = t
+	return t
+}
+
+// setDiscAttribute stores a CINFO attribute on the disc.
+func (p *MakeMKVParser) setDiscAttribute(id int, value string) {
+	d := p.disc
+	d.Attributes[id] = value
+	switch id {
+	case attrType:
+		d.Type = value
+	case attrName:
+		d.Name = value
+	case attrVolumeName:
+		d.VolumeName = value
+	case attrMetadataLangCode:
+		d.Language = value
+	case attrMetadataLangName:
+		d.LanguageName = value
+	}
+}
+
+// setAttribute stores a TINFO attribute on the title.
+func (t *TitleInfo) setAttribute(id int, value string) {
+	t.Attributes[id] = value
+	switch id {
+	case attrName:
+		t.Name = value
+	case attrChapterCount:
+		t.Chapters = atoiOrZero(value)
+	case attrDuration:
+		t.Duration = parseMakeMKVDuration(value)
+	case attrDiskSize:
+		t.Size = value
+	case attrDiskSizeBytes:
+		t.SizeBytes, _ = strconv.ParseInt(value, 10, 64)
+	case attrSourceFileName:
+		t.SourceFileName = value
+	case attrSegmentsCount:
+		t.SegmentCount = atoiOrZero(value)
+	case attrSegmentsMap:
+		t.SegmentMap = value
+	case attrOutputFileName:
+		t.OutputFileName = value
+	case attrMetadataLangCode:
+		t.Language = value
+	case attrComment:
+		t.Comment = value
+	}
+}
+
+// stream returns the stream with the given ID, creating it if needed.
+func (t *TitleInfo) stream(id int) *StreamInfo {
+	for _, s := range t.Streams {
+		if s.ID == id {
+			return s
+		}
+	}
+	s := &StreamInfo{ID: id, Attributes: map[int]string{}}
+	t.Streams = append(t.Streams, s)
+	return s
+}
+
+// setAttribute stores a SINFO attribute on the stream.
+func (s *StreamInfo) setAttribute(id int, value string) {
+	s.Attributes[id] = value
+	switch id {
+	case attrType:
+		s.Type = value
+	case attrCodecShort:
+		s.Codec = value
+	case attrCodecLong:
+		s.CodecLong = value
+	case attrLangCode:
+		s.Language = value
+	case attrLangName:
+		s.LanguageName = value
+	case attrBitrate:
+		s.Bitrate = value
+	case attrAudioChannels:
+		s.Channels = atoiOrZero(value)
+	case attrAudioSampleRate:
+		s.SampleRate = atoiOrZero(value)
+	case attrVideoSize:
+		s.VideoSize = value
+	case attrVideoAspectRatio:
+		s.AspectRatio = value
+	case attrVideoFrameRate:
+		s.FrameRate = value
+	case attrAngleInfo:
+		s.Angle = value
+	}
+}
+
+// StreamsOfType returns the streams of the given type ("Video", "Audio" or "Subtitles").
+func (t *TitleInfo) StreamsOfType(streamType string) []*StreamInfo {
+	var streams []*StreamInfo
+	for _, s := range t.Streams {
+		if s.Type == streamType {
+			streams = append(streams, s)
+		}
+	}
+	return streams
+}
+
+// Languages returns the distinct audio languages of the title in stream order.
+func (t *TitleInfo) Languages() []string {
+	var langs []string
+	seen := map[string]bool{}
+	for _, s := range t.StreamsOfType("Audio") {
+		if s.Language != "" && !seen[s.Language] {
+			seen[s.Language] = true
+			langs = append(langs, s.Language)
+		}
+	}
+	return langs
+}
+
+// maxSegmentRange is the most segments a range in a segment map may span; longer ranges are
+// not real segment maps and are skipped.
+const maxSegmentRange = 1000
+
+// Segments returns the title's segment map as a list of segment numbers.
+// The map uses commas for single segments and dashes for ranges (e.g. "1-3,5").
+func (t *TitleInfo) Segments() []int {
+	var segments []int
+	for _, part := range strings.Split(t.SegmentMap, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if from, to, ok := strings.Cut(part, "-"); ok {
+			start, err1 := strconv.Atoi(from)
+			end, err2 := strconv.Atoi(to)
+			if err1 != nil || err2 != nil || end-start >= maxSegmentRange {
+				continue
+			}
+			for n := start; n <= end; n++ {
+				segments = append(segments, n)
+			}
+			continue
+		}
+		if n, err := strconv.Atoi(part); err == nil {
+			segments = append(segments, n)
+		}
+	}
+	return segments
+}
+
+// Title returns the title with the given ID, or nil if the disc has no such title.
+func (d *DiscInfo) Title(id int) *TitleInfo {
+	for _, t := range d.Titles {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// LongestTitle returns the title with the longest running time, or nil if the disc has no titles.
+func (d *DiscInfo) LongestTitle() *TitleInfo {
+	var longest *TitleInfo
+	for _, t := range d.Titles {
+		if longest == nil || t.Duration > longest.Duration {
+			longest = t
+		}
+	}
+	return longest
+}
+
+// ParseMakeMKVInfo parses the complete output of `makemkvcon -r info` into a DiscInfo.
+func ParseMakeMKVInfo(r io.Reader) (*DiscInfo, error) {
+	p := NewMakeMKVParser()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := p.ParseLine(scanner.Text()); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading makemkvcon output: %v", err)
+	}
+	return p.Disc(), nil
+}
+
+// readDiscInfo queries a disc with `makemkvcon -r info` and parses the result.
+// makemkvcon numbers the titles it keeps after dropping those shorter than --minlength, so the
+// info command has to use the minimum length of the rip for its title IDs to be the ones
+// `makemkvcon mkv` rips.
+//
+// Parameters:
+//
+//	drive - the disc specification (e.g., "disc:0")
+//	minLength - the minimum title length the disc will be ripped with
+//
+// Returns the parsed disc model, or an error if makemkvcon fails or reports no titles.
+func readDiscInfo(drive string, minLength time.Duration) (*DiscInfo, error) {
+	out, err := runWithSpinner("Reading disc...", "makemkvcon", "-r", "info", drive, minLengthFlag(minLength))
+	if err != nil {
+		return nil, fmt.Errorf("error running makemkvcon info: %v", err)
+	}
+
+	disc, err := ParseMakeMKVInfo(strings.NewReader(out))
+	if err != nil {
+		return nil, err
+	}
+	if len(disc.Titles) == 0 {
+		return disc, fmt.Errorf("makemkvcon found no titles on %s", drive)
+	}
+	return disc, nil
+}
+
+// minLengthFlag returns the makemkvcon option that skips titles shorter than d.
+func minLengthFlag(d time.Duration) string {
+	return fmt.Sprintf("--minlength=%d", int(d.Seconds()))
+}
+
+// printTitles prints a summary line for every title on the disc.
+func printTitles(disc *DiscInfo) {
+	fmt.Println("Found titles:")
+	for _, t := range disc.Titles {
+		fmt.Printf("  Title %d: %s, %d chapters, %s", t.ID, formatDuration(t.Duration), t.Chapters, t.Size)
+		if langs := t.Languages(); len(langs) > 0 {
+			fmt.Printf(", audio: %s", strings.Join(langs, "/"))
+		}
+		if t.SourceFileName != "" {
+			fmt.Printf(" (%s)", t.SourceFileName)
+		}
+		fmt.Println()
+	}
+}
+
+// splitRobotLine splits a robot-mode line into its type (e.g. "TINFO") and its fields.
+// Quoted fields are unquoted and backslash escapes inside them are resolved.
+// Returns ok=false for lines that are not robot-mode messages.
+func splitRobotLine(line string) (kind string, fields []string, ok bool) {
+	line = strings.TrimRight(line, "\r\n")
+	kind, rest, found := strings.Cut(line, ":")
+	if !found || kind == "" || strings.ToUpper(kind) != kind || strings.ContainsAny(kind, " \t") {
+		return "", nil, false
+	}
+
+	var field strings.Builder
+	inQuotes := false
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case inQuotes && c == '\\' && i+1 < len(rest):
+			i++
+			field.WriteByte(rest[i])
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == ',' && !inQuotes:
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(c)
+		}
+	}
+	fields = append(fields, field.String())
+	return kind, fields, true
+}
+
+// parseMakeMKVDuration parses a makemkvcon duration such as "1:52:03" or "0:45:10".
+// Returns zero if the value cannot be parsed.
+func parseMakeMKVDuration(value string) time.Duration {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	s, err3 := strconv.Atoi(parts[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+}
+
+// formatDuration formats a duration as "X min Y sec" for display.
+func formatDuration(d time.Duration) string {
+	seconds := int(d.Seconds())
+	return fmt.Sprintf("%d min %d sec", seconds/60, seconds%60)
+}
+
+// atoiOrZero converts s to an int, returning 0 if s is not a number.
+func atoiOrZero(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// parseTestdata parses a captured `makemkvcon -r info` output from testdata.
+func parseTestdata(t *testing.T, name string) *DiscInfo {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	disc, err := ParseMakeMKVInfo(f)
+	if err != nil {
+		t.Fatalf("ParseMakeMKVInfo(%s): %v", name, err)
+	}
+	return disc
+}
+
+// withoutAttributes returns a copy of disc without the raw attribute maps, which the tests
+// check separately, so the typed fields can be compared as a whole.
+func withoutAttributes(disc *DiscInfo) DiscInfo {
+	d := *disc
+	d.Attributes = nil
+	d.Titles = nil
+	for _, t := range disc.Titles {
+		tc := *t
+		tc.Attributes = nil
+		tc.Streams = nil
+		for _, s := range t.Streams {
+			sc := *s
+			sc.Attributes = nil
+			tc.Streams = append(tc.Streams, &sc)
+		}
+		d.Titles = append(d.Titles, &tc)
+	}
+	return d
+}
+
+func TestParseMakeMKVInfo(t *testing.T) {
+	tests := []struct {
+		file string
+		want DiscInfo
+	}{
+		{
+			file: "makemkv_info_dvd.txt",
+			want: DiscInfo{
+				Type:         "DVD disc",
+				Name:         "The Break-Up",
+				VolumeName:   "THE_BREAK_UP",
+				Language:     "eng",
+				LanguageName: "English",
+				TitleCount:   3,
+				Titles: []*TitleInfo{
+					{
+						ID: 0, Name: "The Break-Up", Chapters: 26,
+						Duration: time.Hour + 45*time.Minute + 43*time.Second,
+						Size:     "4.3 GB", SizeBytes: 4675584000,
+						SegmentCount: 1, SegmentMap: "1-26", OutputFileName: "The_Break-Up_t00.mkv", Language: "eng",
+						Streams: []*StreamInfo{
+							{ID: 0, Type: "Video", Codec: "Mpeg2", CodecLong: "Mpeg2", VideoSize: "720x480", AspectRatio: "16:9", FrameRate: "29.97 (30000/1001)"},
+							{ID: 1, Type: "Audio", Codec: "AC3", CodecLong: "Dolby Digital", Language: "eng", LanguageName: "English", Bitrate: "448 Kb/s", Channels: 6, SampleRate: 48000},
+							{ID: 2, Type: "Audio", Codec: "AC3", CodecLong: "Dolby Digital", Language: "fra", LanguageName: "French", Bitrate: "192 Kb/s", Channels: 2, SampleRate: 48000},
+							{ID: 3, Type: "Subtitles", CodecLong: "Dvd Subtitles", Language: "eng", LanguageName: "English"},
+						},
+					},
+					{
+						ID: 1, Name: "The Break-Up", Chapters: 2, Duration: 4*time.Minute + 12*time.Second,
+						Size: "180.2 MB", SizeBytes: 188956672, SegmentCount: 1, SegmentMap: "27,28", OutputFileName: "The_Break-Up_t01.mkv",
+						Streams: []*StreamInfo{
+							{ID: 0, Type: "Video", Codec: "Mpeg2", VideoSize: "720x480"},
+							{ID: 1, Type: "Audio", Codec: "AC3", Language: "eng", Channels: 2},
+						},
+					},
+					{
+						ID: 2, Name: `Making "The Break-Up", Part 1`, Chapters: 5, Duration: 22*time.Minute + 31*time.Second,
+						Size: "967.5 MB", SizeBytes: 1014497280, SegmentCount: 1, SegmentMap: "29", OutputFileName: "The_Break-Up_t02.mkv", Comment: "Bonus",
+						Streams: []*StreamInfo{
+							{ID: 0, Type: "Video", Codec: "Mpeg2", VideoSize: "720x480", AspectRatio: "4:3"},
+						},
+					},
+				},
+				Drives: []DriveInfo{
+					{Index: 0, State: driveStateInserted, Flags: 1, DriveName: "DVD+R-DL MATSHITA DVD-RAM UJ8E2 1.00", DiscName: "THE_BREAK_UP", Device: "/dev/sr0"},
+					{Index: 1, State: driveStateEmptyClosed, Flags: 0, DriveName: "BD-RE HL-DT-ST BD-RE WH16NS40 1.05", Device: "/dev/sr1"},
+				},
+				Messages: []RobotMessage{
+					{Code: 1005, Text: "MakeMKV v1.17.7 linux(x64-release) started", Format: "%1 started", Params: []string{"MakeMKV v1.17.7 linux(x64-release)"}},
+					{Code: 3007, Text: "Using direct disc access mode", Format: "Using direct disc access mode", Params: []string{}},
+					{Code: 3028, Text: "Title #1 was added (26 cell(s), 1:45:43)", Format: "Title #%1 was added (%2 cell(s), %3)", Params: []string{"1", "26", "1:45:43"}},
+					{Code: 5011, Text: "Operation successfully completed", Format: "Operation successfully completed", Params: []string{}},
+				},
+			},
+		},
+		{
+			file: "makemkv_info_bluray.txt",
+			want: DiscInfo{
+				Type:         "Blu-ray disc",
+				Name:         "Inception",
+				VolumeName:   "INCEPTION",
+				Language:     "eng",
+				LanguageName: "English",
+				TitleCount:   2,
+				Titles: []*TitleInfo{
+					{
+						ID: 0, Name: "Inception", Chapters: 28, Duration: 2*time.Hour + 28*time.Minute + 7*time.Second,
+						Size: "32.5 GB", SizeBytes: 34896609280, SourceFileName: "00800.mpls",
+						SegmentCount: 3, SegmentMap: "55,56,57", OutputFileName: "Inception_t00.mkv", Language: "eng",
+						Streams: []*StreamInfo{
+							{ID: 0, Type: "Video", Codec: "Mpeg4", CodecLong: "Mpeg4 AVC High@L4.1", VideoSize: "1920x1080", AspectRatio: "16:9", FrameRate: "23.976 (24000/1001)"},
+							{ID: 1, Type: "Audio", Codec: "DTS-HD MA", CodecLong: "DTS-HD Master Audio", Language: "eng", LanguageName: "English", Bitrate: "3.8 Mb/s", Channels: 6, SampleRate: 48000},
+						},
+					},
+					{
+						ID: 1, Name: "Inception", Chapters: 28, Duration: 2*time.Hour + 28*time.Minute + 7*time.Second,
+						Size: "32.5 GB", SizeBytes: 34896609280, SourceFileName: "00801.mpls",
+						SegmentCount: 3, SegmentMap: "57,55,56", OutputFileName: "Inception_t01.mkv",
+						Streams: []*StreamInfo{
+							{ID: 0, Type: "Video", Codec: "Mpeg4", VideoSize: "1920x1080", Angle: "2"},
+						},
+					},
+				},
+				Drives: []DriveInfo{
+					{Index: 0, State: driveStateInserted, Flags: 12, DriveName: "BD-RE HL-DT-ST BD-RE WH16NS40 1.05", DiscName: "INCEPTION", Device: "/dev/sr0"},
+				},
+				Messages: []RobotMessage{
+					{Code: 1005, Text: "MakeMKV v1.17.7 linux(x64-release) started", Format: "%1 started", Params: []string{"MakeMKV v1.17.7 linux(x64-release)"}},
+					{Code: 3338, Text: "Downloaded latest SDF to /home/user/.MakeMKV", Format: "Downloaded latest SDF to %1", Params: []string{"/home/user/.MakeMKV"}},
+					{Code: 3307, Text: "File 00800.mpls was added as title #0", Format: "File %1 was added as title #%2", Params: []string{"00800.mpls", "0"}},
+					{Code: 3307, Text: "File 00801.mpls was added as title #1", Format: "File %1 was added as title #%2", Params: []string{"00801.mpls", "1"}},
+					{
+						Code:   3025,
+						Text:   "Title #00002.m2ts has length of 98 seconds which is less than minimum title length of 120 seconds and was therefore skipped",
+						Format: "Title #%1 has length of %2 seconds which is less than minimum title length of %3 seconds and was therefore skipped",
+						Params: []string{"00002.m2ts", "98", "120"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			disc := parseTestdata(t, tt.file)
+			diffDisc(t, withoutAttributes(disc), tt.want)
+			// Every attribute is kept, including those without a typed field
+			if disc.Attributes[attrVolumeName] != tt.want.VolumeName {
+				t.Errorf("CINFO attribute %d = %q, want %q", attrVolumeName, disc.Attributes[attrVolumeName], tt.want.VolumeName)
+			}
+			for _, title := range disc.Titles {
+				if title.Attributes[attrDuration] == "" {
+					t.Errorf("title %d: TINFO duration attribute not kept", title.ID)
+				}
+			}
+		})
+	}
+}
+
+// diffDisc reports every part of got that differs from want.
+func diffDisc(t *testing.T, got, want DiscInfo) {
+	t.Helper()
+	gotTitles, wantTitles := got.Titles, want.Titles
+	got.Titles, want.Titles = nil, nil
+	gotDrives, wantDrives := got.Drives, want.Drives
+	got.Drives, want.Drives = nil, nil
+	gotMessages, wantMessages := got.Messages, want.Messages
+	got.Messages, want.Messages = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("disc = %+v, want %+v", got, want)
+	}
+	if len(gotTitles) != len(wantTitles) {
+		t.Fatalf("got %d titles, want %d", len(gotTitles), len(wantTitles))
+	}
+	for i := range wantTitles {
+		g, w := *gotTitles[i], *wantTitles[i]
+		for j := 0; j < len(g.Streams) || j < len(w.Streams); j++ {
+			if j >= len(g.Streams) || j >= len(w.Streams) || !reflect.DeepEqual(g.Streams[j], w.Streams[j]) {
+				t.Errorf("title %d stream %d differs", w.ID, j)
+				for _, s := range g.Streams {
+					t.Logf("got  %+v", *s)
+				}
+				for _, s := range w.Streams {
+					t.Logf("want %+v", *s)
+				}
+				break
+			}
+		}
+		g.Streams, w.Streams = nil, nil
+		if !reflect.DeepEqual(g, w) {
+			t.Errorf("title %d = %+v, want %+v", w.ID, g, w)
+		}
+	}
+	if !reflect.DeepEqual(gotDrives, wantDrives) {
+		t.Errorf("drives = %+v, want %+v", gotDrives, wantDrives)
+	}
+	if !reflect.DeepEqual(gotMessages, wantMessages) {
+		t.Errorf("messages = %+v, want %+v", gotMessages, wantMessages)
+	}
+}
+
+func TestParseLineRejectsMalformedLines(t *testing.T) {
+	for _, line := range []string{
+		`CINFO:x,0,"name"`,
+		`CINFO:2`,
+		`TINFO:0,9`,
+		`TINFO:a,9,0,"1:00:00"`,
+		`SINFO:0,0,1`,
+		`SINFO:0,x,1,0,"Video"`,
+		`TCOUNT:many`,
+		`DRV:0,2,999`,
+		`MSG:1005,0`,
+	} {
+		if err := NewMakeMKVParser().ParseLine(line); err == nil {
+			t.Errorf("ParseLine(%s) = nil, want an error", line)
+		}
+	}
+}
+
+func TestParseLineIgnoresOtherOutput(t *testing.T) {
+	p := NewMakeMKVParser()
+	for _, line := range []string{
+		"",
+		"PRGV:100,200,65536",
+		`PRGT:5018,0,"Scanning CD-ROM devices"`,
+		"Using direct disc access mode",
+		"weird: line without a robot prefix",
+	} {
+		if err := p.ParseLine(line); err != nil {
+			t.Errorf("ParseLine(%q): %v", line, err)
+		}
+	}
+	if d := p.Disc(); len(d.Titles) != 0 || len(d.Messages) != 0 {
+		t.Errorf("other output added to the disc: %+v", d)
+	}
+}
+
+func TestTitleSegments(t *testing.T) {
+	tests := []struct {
+		segmentMap string
+		want       []int
+	}{
+		{"", nil},
+		{"1-26", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26}},
+		{"55,56,57", []int{55, 56, 57}},
+		{"57,55,56", []int{57, 55, 56}},
+		{"1-3,7, 9-10", []int{1, 2, 3, 7, 9, 10}},
+		{"00800,00801", []int{800, 801}},
+		{"x,4", []int{4}},
+		{"1-5000,7", []int{7}},
+	}
+	for _, tt := range tests {
+		got := (&TitleInfo{SegmentMap: tt.segmentMap}).Segments()
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Segments(%q) = %v, want %v", tt.segmentMap, got, tt.want)
+		}
+	}
+}
+
+func TestParseMakeMKVDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"1:52:03", time.Hour + 52*time.Minute + 3*time.Second},
+		{"0:45:10", 45*time.Minute + 10*time.Second},
+		{"45:10", 0},
+		{"", 0},
+		{"a:b:c", 0},
+	}
+	for _, tt := range tests {
+		if got := parseMakeMKVDuration(tt.value); got != tt.want {
+			t.Errorf("parseMakeMKVDuration(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDiscLongestTitle(t *testing.T) {
+	disc := parseTestdata(t, "makemkv_info_dvd.txt")
+	if got := disc.LongestTitle(); got == nil || got.ID != 0 {
+		t.Errorf("LongestTitle() = %+v, want title 0", got)
+	}
+	if got := disc.Title(2); got == nil || got.Comment != "Bonus" {
+		t.Errorf("Title(2) = %+v, want the bonus title", got)
+	}
+	if got := disc.Title(9); got != nil {
+		t.Errorf("Title(9) = %+v, want nil", got)
+	}
+	if got := disc.Titles[0].Languages(); !slices.Equal(got, []string{"eng", "fra"}) {
+		t.Errorf("Languages() = %v, want [eng fra]", got)
+	}
+}
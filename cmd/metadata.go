@@ -0,0 +1,482 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Metadata backends selectable with metadata.backend in ~/.rip.yaml.
+const (
+	metadataBackendAuto    = "auto"    // TMDB when metadata.tmdb_api_key is set, otherwise FileBot
+	metadataBackendTMDB    = "tmdb"    // Built-in TMDB client
+	metadataBackendFileBot = "filebot" // FileBot's -list command
+)
+
+// MetadataResult is a movie or TV show found by a metadata backend.
+type MetadataResult struct {
+	ID       int      // TMDB ID
+	Title    string   // Movie title or show name
+	Year     int      // Release or first air year; 0 when unknown
+	Overview string   // Plot summary
+	Genres   []string // Genre names, if known
+	IMDBID   string   // IMDb ID (e.g. "tt0133093"), if known
+	TVDBID   int      // TheTVDB ID for shows, if known
+}
+
+// MetadataProvider looks up movies and TV shows.
+// Search results are returned in the backend's relevance order.
+type MetadataProvider interface {
+	SearchMovies(query string) ([]MetadataResult, error)
+	SearchShows(query string) ([]MetadataResult, error)
+	Movie(id int) (*MetadataResult, error)
+	Show(id int) (*MetadataResult, error)
+	// Episodes returns the episode titles of a season of a show, keyed by episode number
+	Episodes(show *MetadataResult, season int) (map[int]string, error)
+}
+
+// newMetadataProvider returns the provider selected by the metadata.backend setting.
+// Its calls are limited to limits.metadata_jobs at a time across all running rips;
+// ctx stops the wait for a free slot.
+func newMetadataProvider(ctx context.Context) (MetadataProvider, error) {
+	switch metadataBackend() {
+	case metadataBackendTMDB:
+		client := NewTMDBClient(AppConfig.Metadata.TMDBAPIKey)
+		if AppConfig.Metadata.TMDBBaseURL != "" {
+			client.BaseURL = AppConfig.Metadata.TMDBBaseURL
+		}
+		client.Language = AppConfig.Metadata.TMDBLanguage
+		return limitedProvider{ctx, &tmdbProvider{client: client}}, nil
+	case metadataBackendFileBot:
+		return limitedProvider{ctx, fileBotProvider{}}, nil
+	default:
+		return nil, fmt.Errorf("unknown metadata.backend %q (use auto, tmdb or filebot)", AppConfig.Metadata.Backend)
+	}
+}
+
+// limitedProvider holds a metadata slot (see acquireSlot) for every call to the wrapped provider.
+type limitedProvider struct {
+	ctx      context.Context
+	provider MetadataProvider
+}
+
+// SearchMovies searches the wrapped provider while holding a metadata slot.
+func (p limitedProvider) SearchMovies(query string) ([]MetadataResult, error) {
+	release, err := acquireSlot(p.ctx, nil, slotMetadata, AppConfig.Limits.MetadataJobs)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.provider.SearchMovies(query)
+}
+
+// SearchShows searches the wrapped provider while holding a metadata slot.
+func (p limitedProvider) SearchShows(query string) ([]MetadataResult, error) {
+	release, err := acquireSlot(p.ctx, nil, slotMetadata, AppConfig.Limits.MetadataJobs)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.provider.SearchShows(query)
+}
+
+// Movie fetches movie details from the wrapped provider while holding a metadata slot.
+func (p limitedProvider) Movie(id int) (*MetadataResult, error) {
+	release, err := acquireSlot(p.ctx, nil, slotMetadata, AppConfig.Limits.MetadataJobs)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.provider.Movie(id)
+}
+
+// Show fetches show details from the wrapped provider while holding a metadata slot.
+func (p limitedProvider) Show(id int) (*MetadataResult, error) {
+	release, err := acquireSlot(p.ctx, nil, slotMetadata, AppConfig.Limits.MetadataJobs)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.provider.Show(id)
+}
+
+// Episodes fetches episode titles from the wrapped provider while holding a metadata slot.
+func (p limitedProvider) Episodes(show *MetadataResult, season int) (map[int]string, error) {
+	release, err := acquireSlot(p.ctx, nil, slotMetadata, AppConfig.Limits.MetadataJobs)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.provider.Episodes(show, season)
+}
+
+// metadataBackend resolves the configured backend, turning "auto" into tmdb or filebot.
+func metadataBackend() string {
+	backend := AppConfig.Metadata.Backend
+	if backend == "" || backend == metadataBackendAuto {
+		if AppConfig.Metadata.TMDBAPIKey != "" {
+			return metadataBackendTMDB
+		}
+		return metadataBackendFileBot
+	}
+	return backend
+}
+
+// maxCandidates is the number of search results offered when a lookup is ambiguous.
+const maxCandidates = 5
+
+// matchOptions controls how a match is chosen when a lookup returns several results.
+type matchOptions struct {
+	AssumeYes bool // Take the best match without asking (--yes)
+	Pick      int  // Take the Nth search result, 1-based (--pick); 0 means not set
+	TMDBID    int  // Skip the search and look up this TMDB ID directly (--tmdb-id); 0 means not set
+}
+
+// matchOptionsFromFlags reads the --yes, --pick and --tmdb-id flags of a command.
+func matchOptionsFromFlags(cmd *cobra.Command) matchOptions {
+	var opts matchOptions
+	opts.AssumeYes, _ = cmd.Flags().GetBool("yes")
+	opts.Pick, _ = cmd.Flags().GetInt("pick")
+	opts.TMDBID, _ = cmd.Flags().GetInt("tmdb-id")
+	return opts
+}
+
+// addMatchFlags registers the flags read by matchOptionsFromFlags.
+func addMatchFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("yes", "y", false, "Use the best metadata match without asking")
+	cmd.Flags().Int("pick", 0, "Use the Nth metadata match (1-based) without asking")
+	cmd.Flags().Int("tmdb-id", 0, "Skip the metadata search and use this TMDB ID")
+}
+
+// lookupMovie searches for a movie and returns the match chosen according to opts.
+// Returns nil if the search fails, nothing matches, or the user rejects every candidate.
+// Returns an error only when the choice itself fails (an invalid --pick or a failed --tmdb-id lookup).
+func lookupMovie(ctx context.Context, query string, opts matchOptions) (*MetadataResult, error) {
+	provider, err := newMetadataProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if opts.TMDBID > 0 {
+		return provider.Movie(opts.TMDBID)
+	}
+
+	results, err := provider.SearchMovies(query)
+	if err != nil {
+		log.Printf("Error fetching metadata: %v\n", err)
+		return nil, nil
+	}
+	return chooseMatch(query, results, opts, os.Stdin, os.Stdout)
+}
+
+// lookupShow searches for a TV show and returns the match chosen according to opts,
+// with its full details (genres and external IDs).
+// Returns nil if the search fails, nothing matches, or the user rejects every candidate.
+// Returns an error only when the choice itself fails (an invalid --pick or a failed --tmdb-id lookup).
+func lookupShow(ctx context.Context, query string, opts matchOptions) (*MetadataResult, error) {
+	provider, err := newMetadataProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if opts.TMDBID > 0 {
+		return provider.Show(opts.TMDBID)
+	}
+
+	results, err := provider.SearchShows(query)
+	if err != nil {
+		log.Printf("Error fetching metadata: %v\n", err)
+		return nil, nil
+	}
+	match, err := chooseMatch(query, results, opts, os.Stdin, os.Stdout)
+	if match == nil || err != nil {
+		return match, err
+	}
+
+	// TMDB search results do not carry genre names, so fetch the details of the chosen match
+	if len(match.Genres) > 0 {
+		return match, nil
+	}
+	show, err := provider.Show(match.ID)
+	if err != nil {
+		log.Printf("Warning: could not fetch show details: %v\n", err)
+		return match, nil
+	}
+	return show, nil
+}
+
+// chooseMatch picks one of the search results.
+// A single result, --yes, or a non-interactive stdin selects the first result; --pick selects
+// the Nth result; otherwise the top candidates are listed on out and the user chooses one from in.
+//
+// Returns nil if there are no results or the user chooses none of them.
+func chooseMatch(query string, results []MetadataResult, opts matchOptions, in *os.File, out io.Writer) (*MetadataResult, error) {
+	if len(results) == 0 {
+		return nil, nil
+	}
+	if opts.Pick > 0 {
+		if opts.Pick > len(results) {
+			return nil, fmt.Errorf("--pick %d is out of range: the search for %q returned %d results", opts.Pick, query, len(results))
+		}
+		return &results[opts.Pick-1], nil
+	}
+	if len(results) == 1 || opts.AssumeYes {
+		return &results[0], nil
+	}
+	if !isTerminal(in) {
+		fmt.Fprintf(out, "Warning: %d matches for %q and no terminal to ask; using the first one (use --pick or --tmdb-id to choose)\n", len(results), query)
+		return &results[0], nil
+	}
+
+	candidates := results
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+	fmt.Fprintf(out, "Multiple matches for %q:\n", query)
+	for i, c := range candidates {
+		fmt.Fprintf(out, "  %d) %s [tmdb %d]\n", i+1, matchLabel(&c), c.ID)
+		if c.Overview != "" {
+			fmt.Fprintf(out, "     %s\n", truncate(c.Overview, 100))
+		}
+	}
+	fmt.Fprintf(out, "  0) None of these (use %q as given)\n", query)
+
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Fprintf(out, "Select [1]: ")
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if err != nil {
+				// EOF without an answer: keep the default
+				fmt.Fprintln(out)
+			}
+			return &candidates[0], nil
+		}
+		choice, convErr := strconv.Atoi(line)
+		if convErr == nil && choice == 0 {
+			return nil, nil
+		}
+		if convErr == nil && choice >= 1 && choice <= len(candidates) {
+			return &candidates[choice-1], nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection: %s", line)
+		}
+		fmt.Fprintf(out, "Please enter a number from 0 to %d.\n", len(candidates))
+	}
+}
+
+// matchLabel describes a search result for the user: "Movie Name (Year)".
+func matchLabel(m *MetadataResult) string {
+	if m.Year == 0 {
+		return m.Title
+	}
+	return fmt.Sprintf("%s (%d)", m.Title, m.Year)
+}
+
+// tmdbProvider looks up metadata with the built-in TMDB client.
+type tmdbProvider struct {
+	client *TMDBClient
+}
+
+// SearchMovies searches TMDB for movies.
+func (p *tmdbProvider) SearchMovies(query string) ([]MetadataResult, error) {
+	movies, err := p.client.SearchMovie(query, 0)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]MetadataResult, 0, len(movies))
+	for _, m := range movies {
+		results = append(results, movieResult(&m))
+	}
+	return results, nil
+}
+
+// SearchShows searches TMDB for TV shows.
+func (p *tmdbProvider) SearchShows(query string) ([]MetadataResult, error) {
+	shows, err := p.client.SearchTV(query, 0)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]MetadataResult, 0, len(shows))
+	for _, s := range shows {
+		results = append(results, showResult(&s))
+	}
+	return results, nil
+}
+
+// Movie returns the details and IMDb ID of a movie.
+func (p *tmdbProvider) Movie(id int) (*MetadataResult, error) {
+	movie, err := p.client.MovieDetails(id)
+	if err != nil {
+		return nil, err
+	}
+	result := movieResult(movie)
+	return &result, nil
+}
+
+// Show returns the details and external IDs of a TV show.
+func (p *tmdbProvider) Show(id int) (*MetadataResult, error) {
+	show, err := p.client.TVDetails(id)
+	if err != nil {
+		return nil, err
+	}
+	result := showResult(show)
+	if ids, err := p.client.TVExternalIDs(id); err == nil {
+		result.IMDBID = ids.IMDBID
+		result.TVDBID = ids.TVDBID
+	}
+	return &result, nil
+}
+
+// Episodes returns the episode titles of a season from TMDB.
+func (p *tmdbProvider) Episodes(show *MetadataResult, season int) (map[int]string, error) {
+	s, err := p.client.TVSeason(show.ID, season)
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[int]string, len(s.Episodes))
+	for _, e := range s.Episodes {
+		titles[e.EpisodeNumber] = e.Name
+	}
+	return titles, nil
+}
+
+// movieResult converts a TMDB movie into a MetadataResult.
+func movieResult(m *TMDBMovie) MetadataResult {
+	result := MetadataResult{
+		ID:       m.ID,
+		Title:    m.Title,
+		Year:     releaseYear(m.ReleaseDate),
+		Overview: m.Overview,
+		IMDBID:   m.IMDBID,
+	}
+	for _, g := range m.Genres {
+		result.Genres = append(result.Genres, g.Name)
+	}
+	return result
+}
+
+// showResult converts a TMDB show into a MetadataResult.
+func showResult(s *TMDBShow) MetadataResult {
+	result := MetadataResult{
+		ID:       s.ID,
+		Title:    s.Name,
+		Year:     releaseYear(s.FirstAirDate),
+		Overview: s.Overview,
+	}
+	for _, g := range s.Genres {
+		result.Genres = append(result.Genres, g.Name)
+	}
+	return result
+}
+
+// fileBotProvider looks up metadata with FileBot's -list command.
+// It needs a licensed FileBot installation.
+type fileBotProvider struct{}
+
+// fileBotListFormat makes FileBot print one tab separated record per line.
+const fileBotListFormat = "{n}\t{y}\t{id}\t{genre}"
+
+// SearchMovies searches TheMovieDB through FileBot.
+func (fileBotProvider) SearchMovies(query string) ([]MetadataResult, error) {
+	return fileBotList(AppConfig.FileBot.MovieDB, query)
+}
+
+// SearchShows searches TheMovieDB's TV database through FileBot.
+func (fileBotProvider) SearchShows(query string) ([]MetadataResult, error) {
+	return fileBotList(AppConfig.FileBot.ShowDB, query)
+}
+
+// Movie is not supported by FileBot; lookups by ID need the tmdb backend.
+func (fileBotProvider) Movie(id int) (*MetadataResult, error) {
+	return nil, fmt.Errorf("looking up TMDB ID %d requires metadata.backend: tmdb", id)
+}
+
+// Show is not supported by FileBot; lookups by ID need the tmdb backend.
+func (fileBotProvider) Show(id int) (*MetadataResult, error) {
+	return nil, fmt.Errorf("looking up TMDB ID %d requires metadata.backend: tmdb", id)
+}
+
+// fileBotEpisodeFormat makes FileBot print the season, episode number and title of every episode.
+const fileBotEpisodeFormat = "{s}\t{e}\t{t}"
+
+// Episodes lists the episodes of a show from filebot.episode_db and returns the titles of a season.
+func (fileBotProvider) Episodes(show *MetadataResult, season int) (map[int]string, error) {
+	out, err := runWithSpinner("Querying episode titles...", "filebot", "-list", "--db", AppConfig.FileBot.EpisodeDB, "--q", show.Title, "--format", fileBotEpisodeFormat)
+	if err != nil {
+		return nil, err
+	}
+	titles := map[int]string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), "\t", 3)
+		if len(fields) < 3 || atoiOrZero(fields[0]) != season {
+			continue
+		}
+		if episode, err := strconv.Atoi(fields[1]); err == nil {
+			titles[episode] = fields[2]
+		}
+	}
+	return titles, nil
+}
+
+// lookupEpisodeTitles returns the episode titles of a season of a matched show.
+// Titles are optional in file names, so a failed lookup is only a warning.
+//
+// Parameters:
+//
+//	ctx - stops the wait for a free metadata slot
+//	show - the show found by lookupShow
+//	season - the season number
+//
+// Returns the titles keyed by episode number, or nil if they could not be looked up.
+func lookupEpisodeTitles(ctx context.Context, show *MetadataResult, season int) map[int]string {
+	provider, err := newMetadataProvider(ctx)
+	if err != nil {
+		fmt.Printf("Warning: Could not look up episode titles: %v\n", err)
+		return nil
+	}
+	titles, err := provider.Episodes(show, season)
+	if err != nil {
+		fmt.Printf("Warning: Could not look up episode titles: %v\n", err)
+		return nil
+	}
+	return titles
+}
+
+// fileBotList runs `filebot -list` against db and parses its tab separated records.
+// FileBot prints one line per episode for TV databases, so duplicate IDs are dropped.
+func fileBotList(db, query string) ([]MetadataResult, error) {
+	out, err := runWithSpinner("Querying TMDB...", "filebot", "-list", "--db", db, "--q", query, "--format", fileBotListFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []MetadataResult
+	seen := map[int]bool{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) < 3 || fields[0] == "" {
+			continue
+		}
+		id, err := strconv.Atoi(fields[2])
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		result := MetadataResult{ID: id, Title: fields[0]}
+		result.Year, _ = strconv.Atoi(fields[1])
+		if len(fields) > 3 && fields[3] != "" {
+			result.Genres = []string{fields[3]}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
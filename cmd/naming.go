@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Naming presets selectable with naming.preset.
+const (
+	namingPresetPlex     = "plex"     // Plex naming: "Movie (Year) {edition-Name}", "{tmdb-ID}" folder tags
+	namingPresetJellyfin = "jellyfin" // Jellyfin naming: "Movie (Year) - Name", "[tmdbid-ID]" folder tags
+)
+
+// namingPresets holds the templates of each preset. A template left empty in the naming
+// section of the config file comes from the selected preset.
+var namingPresets = map[string]NamingConfig{
+	namingPresetPlex: {
+		MovieFolder:  `{{.Title}}{{with .Year}} ({{.}}){{end}}`,
+		MovieFile:    `{{.Title}}{{with .Year}} ({{.}}){{end}}{{with .Edition}} {edition-{{.}}}{{end}}`,
+		ShowFolder:   `{{camel .Genre}}/{{.Title}}{{with .Year}} ({{.}}){{end}}{{with .TMDBID}} {tmdb-{{.}}}{{end}}`,
+		SeasonFolder: `Season {{pad .Season 2}}`,
+		EpisodeFile:  `{{.Title}} - S{{pad .Season 2}}E{{pad .Episode 2}}{{with .EpisodeTitle}} - {{.}}{{end}}`,
+	},
+	namingPresetJellyfin: {
+		MovieFolder:  `{{.Title}}{{with .Year}} ({{.}}){{end}}{{with .TMDBID}} [tmdbid-{{.}}]{{end}}`,
+		MovieFile:    `{{.Title}}{{with .Year}} ({{.}}){{end}}{{with .Edition}} - {{.}}{{end}}`,
+		ShowFolder:   `{{camel .Genre}}/{{.Title}}{{with .Year}} ({{.}}){{end}}{{with .TMDBID}} [tmdbid-{{.}}]{{end}}`,
+		SeasonFolder: `Season {{pad .Season 2}}`,
+		EpisodeFile:  `{{.Title}} S{{pad .Season 2}}E{{pad .Episode 2}}{{with .EpisodeTitle}} - {{.}}{{end}}`,
+	},
+}
+
+// namingKeys lists the template keys of the naming section, in the order they are checked.
+var namingKeys = []string{"movie_folder", "movie_file", "show_folder", "season_folder", "episode_file"}
+
+// NamingData is what the naming templates are filled in with, e.g. {{.Title}} or {{pad .Episode 2}}.
+type NamingData struct {
+	Title        string // Movie title or show name
+	Year         int    // Release or first air year; 0 when unknown
+	TMDBID       int    // TMDB ID; 0 when unknown
+	IMDBID       string // IMDb ID (e.g. "tt0133093"), if known
+	TVDBID       int    // TheTVDB ID of a show, if known
+	Genre        string // First genre, "Unknown" when there is none
+	Season       int    // Season number of a show
+	Episode      int    // Episode number within the season
+	EpisodeTitle string // Episode title, if the metadata backend knows it
+	Edition      string // Edition of a movie (e.g. "Director's Cut"), if any
+	Resolution   string // Height of the main video stream (e.g. "1080p"), if known
+}
+
+// namingFuncs are the functions available in naming templates besides the text/template built-ins.
+var namingFuncs = template.FuncMap{
+	// pad writes a number with at least width digits: {{pad .Season 2}} -> "01"
+	"pad": func(n, width int) string {
+		return fmt.Sprintf("%0*d", width, n)
+	},
+	// camel writes a string in CamelCase: {{camel .Genre}} -> "ScienceFiction"
+	"camel": toCamelCase,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// namingSample is the data naming templates are tried with when the config is validated.
+var namingSample = NamingData{
+	Title:        "Sample",
+	Year:         2000,
+	TMDBID:       1,
+	IMDBID:       "tt0000001",
+	TVDBID:       1,
+	Genre:        "Drama",
+	Season:       1,
+	Episode:      2,
+	EpisodeTitle: "Pilot",
+	Edition:      "Extended",
+	Resolution:   "1080p",
+}
+
+// namingData returns the naming data of a movie or show found by a metadata backend.
+// When there is no match, only the title is known: the name the user gave.
+func namingData(match *MetadataResult, title string) NamingData {
+	data := NamingData{Title: title, Genre: "Unknown"}
+	if match == nil {
+		return data
+	}
+	data.Title = match.Title
+	data.Year = match.Year
+	data.TMDBID = match.ID
+	data.IMDBID = match.IMDBID
+	data.TVDBID = match.TVDBID
+	if len(match.Genres) > 0 {
+		data.Genre = match.Genres[0]
+	}
+	return data
+}
+
+// template returns the template of a naming key, from the naming section or else from the preset.
+func (n NamingConfig) template(key string) string {
+	templates := map[string]string{
+		"movie_folder":  n.MovieFolder,
+		"movie_file":    n.MovieFile,
+		"show_folder":   n.ShowFolder,
+		"season_folder": n.SeasonFolder,
+		"episode_file":  n.EpisodeFile,
+	}
+	if text := templates[key]; text != "" {
+		return text
+	}
+	preset, ok := namingPresets[n.Preset]
+	if !ok {
+		preset = namingPresets[namingPresetPlex]
+	}
+	return preset.template(key)
+}
+
+// renderName fills in a naming template.
+//
+// Parameters:
+//
+//	key - the naming key of the template, e.g. "episode_file"; used in errors
+//	text - the template
+//	data - the values to fill in
+//
+// Returns the name with surrounding spaces removed, or an error if the template is invalid
+// or renders to an empty name.
+func renderName(key, text string, data NamingData) (string, error) {
+	tmpl, err := template.New(key).Funcs(namingFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("naming.%s: %v", key, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("naming.%s: %v", key, err)
+	}
+	name := strings.TrimSpace(b.String())
+	if name == "" {
+		return "", fmt.Errorf("naming.%s: the template gives an empty name", key)
+	}
+	return name, nil
+}
+
+// name renders the template of a naming key with the naming settings in use.
+func (n NamingConfig) name(key string, data NamingData) (string, error) {
+	return renderName(key, n.template(key), data)
+}
+
+// generatedName renders the template of a naming key and makes the result safe to create
+// with the paths rules: the data is cleaned before it is filled in, and every folder of the
+// result after (see sanitize.go).
+func generatedName(key string, data NamingData) (string, error) {
+	name, err := AppConfig.Naming.name(key, AppConfig.Paths.cleanData(data))
+	if err != nil {
+		return "", err
+	}
+	reserve := 0
+	if strings.HasSuffix(key, "_file") {
+		reserve = fileNameReserve
+	}
+	return AppConfig.Paths.cleanPath(name, reserve), nil
+}
+
+// movieFolderName returns the folder of a movie under its category, e.g. "Movie Name (Year)".
+func movieFolderName(data NamingData) (string, error) {
+	return generatedName("movie_folder", data)
+}
+
+// movieFileName returns the file name of a movie without the .mkv extension.
+func movieFileName(data NamingData) (string, error) {
+	return generatedName("movie_file", data)
+}
+
+// showFolderPath returns the path of a show relative to the storage path,
+// e.g. "Genre/Show Name (Year) {tmdb-ID}".
+func showFolderPath(data NamingData) (string, error) {
+	return generatedName("show_folder", data)
+}
+
+// seasonFolderName returns the folder of a season under its show, e.g. "Season 01".
+func seasonFolderName(season int) (string, error) {
+	return generatedName("season_folder", NamingData{Season: season})
+}
+
+// episodeFileName returns the file name of an episode without the .mkv extension.
+func episodeFileName(data NamingData) (string, error) {
+	return generatedName("episode_file", data)
+}
+
+// showFolderDepth returns how many folders deep shows are under the storage path
+// (2 for "Genre/Show"), so rip tv status knows where to look for them.
+func showFolderDepth() int {
+	path, err := generatedName("show_folder", namingSample)
+	if err != nil {
+		return 2
+	}
+	return len(strings.Split(path, "/"))
+}
+
+// validate checks the naming section: the preset must exist, every template must work
+// with sample data, file names must not contain folders, and episode file names must
+// carry the season and episode as SxxEyy, which rip tv reads back to number later discs.
+// Returns one message per problem.
+func (n NamingConfig) validate() []string {
+	var problems []string
+	if _, ok := namingPresets[n.Preset]; !ok {
+		problems = append(problems, fmt.Sprintf("naming.preset must be %s or %s, got %q", namingPresetPlex, namingPresetJellyfin, n.Preset))
+	}
+	for _, key := range namingKeys {
+		name, err := n.name(key, namingSample)
+		switch {
+		case err != nil:
+			problems = append(problems, err.Error())
+		case strings.HasSuffix(key, "_file") && strings.Contains(name, "/"):
+			// Checked before the paths rules, which would turn the / into a -
+			problems = append(problems, fmt.Sprintf("naming.%s must give a file name without folders, got %q", key, name))
+		case key == "episode_file":
+			m := episodeNumberPattern.FindStringSubmatch(name)
+			if m == nil || atoiOrZero(m[1]) != namingSample.Season || atoiOrZero(m[2]) != namingSample.Episode {
+				problems = append(problems, fmt.Sprintf("naming.episode_file must contain the season and episode as S{{pad .Season 2}}E{{pad .Episode 2}}, got %q", name))
+			}
+		}
+	}
+	return problems
+}
+
+// videoResolution returns the height of a title's first video stream as e.g. "1080p",
+// or an empty string if MakeMKV did not report it.
+func videoResolution(t *TitleInfo) string {
+	if t == nil {
+		return ""
+	}
+	for _, s := range t.Streams {
+		if s.Type != "Video" {
+			continue
+		}
+		_, height, ok := strings.Cut(s.VideoSize, "x")
+		if h, err := strconv.Atoi(height); ok && err == nil && h > 0 {
+			return strconv.Itoa(h) + "p"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNamingPresets(t *testing.T) {
+	movie := NamingData{Title: "The Matrix", Year: 1999, TMDBID: 603, Genre: "Science Fiction"}
+	edition := movie
+	edition.Edition = "Director's Cut"
+	unknown := NamingData{Title: "Home Movies", Genre: "Unknown"}
+	episode := NamingData{Title: "The Office", Year: 2005, TMDBID: 2316, Genre: "Comedy", Season: 2, Episode: 1, EpisodeTitle: "The Dundies"}
+	untitled := episode
+	untitled.EpisodeTitle = ""
+
+	tests := []struct {
+		preset string
+		key    string
+		data   NamingData
+		want   string
+	}{
+		{namingPresetPlex, "movie_folder", movie, "The Matrix (1999)"},
+		{namingPresetPlex, "movie_folder", unknown, "Home Movies"},
+		{namingPresetPlex, "movie_file", movie, "The Matrix (1999)"},
+		{namingPresetPlex, "movie_file", edition, "The Matrix (1999) {edition-Director's Cut}"},
+		{namingPresetPlex, "show_folder", episode, "Comedy/The Office (2005) {tmdb-2316}"},
+		{namingPresetPlex, "show_folder", unknown, "Unknown/Home Movies"},
+		{namingPresetPlex, "season_folder", episode, "Season 02"},
+		{namingPresetPlex, "episode_file", episode, "The Office - S02E01 - The Dundies"},
+		{namingPresetPlex, "episode_file", untitled, "The Office - S02E01"},
+
+		{namingPresetJellyfin, "movie_folder", movie, "The Matrix (1999) [tmdbid-603]"},
+		{namingPresetJellyfin, "movie_folder", unknown, "Home Movies"},
+		{namingPresetJellyfin, "movie_file", movie, "The Matrix (1999)"},
+		{namingPresetJellyfin, "movie_file", edition, "The Matrix (1999) - Director's Cut"},
+		{namingPresetJellyfin, "show_folder", episode, "Comedy/The Office (2005) [tmdbid-2316]"},
+		{namingPresetJellyfin, "season_folder", episode, "Season 02"},
+		{namingPresetJellyfin, "episode_file", episode, "The Office S02E01 - The Dundies"},
+		{namingPresetJellyfin, "episode_file", untitled, "The Office S02E01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preset+" "+tt.key+" "+tt.want, func(t *testing.T) {
+			AppConfig = defaultConfig()
+			AppConfig.Naming.Preset = tt.preset
+			got, err := generatedName(tt.key, tt.data)
+			if err != nil {
+				t.Fatalf("generatedName(%s): %v", tt.key, err)
+			}
+			if got != tt.want {
+				t.Errorf("generatedName(%s) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+
+	// "Science Fiction" becomes one folder with camel
+	AppConfig = defaultConfig()
+	if got, _ := showFolderPath(movie); got != "ScienceFiction/The Matrix (1999) {tmdb-603}" {
+		t.Errorf("showFolderPath = %q", got)
+	}
+}
+
+func TestRenderNameErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"unknown field", "{{.Title}} ({{.Director}})", "can't evaluate field Director"},
+		{"unknown function", "{{title .Title}}", `naming.movie_file: template: movie_file:1: function "title" not defined`},
+		{"unclosed action", "{{.Title", "naming.movie_file: template: movie_file:1: unclosed action"},
+		{"empty name", "{{with .Edition}}{{.}}{{end}}  ", "naming.movie_file: the template gives an empty name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := renderName("movie_file", tt.text, NamingData{Title: "Heat"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("renderName(%q) error = %v, want %q", tt.text, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamingValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		naming NamingConfig
+		want   string // Part of the only problem, or empty for none
+	}{
+		{"plex preset", NamingConfig{Preset: namingPresetPlex}, ""},
+		{"jellyfin preset", NamingConfig{Preset: namingPresetJellyfin}, ""},
+		{"unknown preset", NamingConfig{Preset: "kodi"}, `naming.preset must be plex or jellyfin, got "kodi"`},
+		{"own episode file", NamingConfig{Preset: namingPresetPlex, EpisodeFile: "{{.Title}} {{.Season}}x{{pad .Episode 2}} S{{pad .Season 2}}E{{pad .Episode 2}}"}, ""},
+		{"episode file without SxxEyy", NamingConfig{Preset: namingPresetPlex, EpisodeFile: "{{.Title}} {{.Season}}x{{pad .Episode 2}}"},
+			`naming.episode_file must contain the season and episode as S{{pad .Season 2}}E{{pad .Episode 2}}, got "Sample 1x02"`},
+		{"episode file with a fixed number", NamingConfig{Preset: namingPresetPlex, EpisodeFile: "{{.Title}} - S01E01"},
+			`naming.episode_file must contain the season and episode`},
+		{"file name with a folder", NamingConfig{Preset: namingPresetPlex, MovieFile: "{{.Title}}/{{.Title}}"},
+			`naming.movie_file must give a file name without folders, got "Sample/Sample"`},
+		{"folder with a subfolder", NamingConfig{Preset: namingPresetPlex, ShowFolder: "TV/{{.Title}}"}, ""},
+		{"template error", NamingConfig{Preset: namingPresetPlex, SeasonFolder: "Season {{pad .Season}}"}, "naming.season_folder:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := tt.naming.validate()
+			switch {
+			case tt.want == "" && len(problems) > 0:
+				t.Errorf("validate = %q, want no problems", problems)
+			case tt.want != "" && (len(problems) != 1 || !strings.Contains(problems[0], tt.want)):
+				t.Errorf("validate = %q, want %q", problems, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Placement policies for the placement_policy setting. They decide which MergerFS branch a rip is
+// written to, and are named after the mergerfs create policies they mirror.
+const (
+	placementMostFree     = "mfs"  // The branch with the most free space
+	placementExistingPath = "epff" // The first branch that already has the rip's folder (or the deepest part of it)
+	placementLeastUsed    = "lus"  // The branch with the least used space
+)
+
+// placementPolicies lists the valid placement_policy values.
+var placementPolicies = []string{placementMostFree, placementExistingPath, placementLeastUsed}
+
+// minBranchFreeSpace is the free space a branch needs to be considered for a rip at all.
+const minBranchFreeSpace = 5 * 1024 * 1024 * 1024
+
+// branchSpace is the space on one MergerFS branch.
+type branchSpace struct {
+	Path string
+	Free uint64
+	Used uint64
+}
+
+// branchSpaceOf reads the space of a branch; tests replace it to place rips on made-up branches.
+var branchSpaceOf = readBranchSpace
+
+// chooseBranch picks the MergerFS branch a rip is written to, following placement_policy.
+// The rip is written straight to the branch, so it shows up in the pool at libraryDir without
+// depending on the pool's own create policy. Branches with less than 5 GB free are never chosen.
+//
+// Parameters:
+//
+//	branches - the branches of the pool, in fstab order
+//	libraryDir - the directory the rip goes to, under the storage path
+//
+// Returns the branch, or an error if no branch is usable.
+func chooseBranch(branches []string, libraryDir string) (string, error) {
+	var usable []branchSpace
+	for _, b := range branches {
+		space, err := branchSpaceOf(b)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+		if space.Free < minBranchFreeSpace {
+			fmt.Printf("Skipping MergerFS branch %s: only %s free\n", b, formatBytes(space.Free))
+			continue
+		}
+		usable = append(usable, space)
+	}
+	if len(usable) == 0 {
+		return "", fmt.Errorf("no MergerFS branch has at least %s free", formatBytes(minBranchFreeSpace))
+	}
+
+	policy := AppConfig.PlacementPolicy
+	if policy == placementExistingPath {
+		if branch, existing := existingPathBranch(usable, libraryDir); branch != "" {
+			fmt.Printf("Placing rip on MergerFS branch %s (already has %s)\n", branch, existing)
+			return branch, nil
+		}
+		// Nothing of the path exists yet (e.g. a new show); place it like mfs
+		policy = placementMostFree
+	}
+
+	best := usable[0]
+	for _, s := range usable[1:] {
+		if (policy == placementLeastUsed && s.Used < best.Used) || (policy != placementLeastUsed && s.Free > best.Free) {
+			best = s
+		}
+	}
+	if policy == placementLeastUsed {
+		fmt.Printf("Placing rip on MergerFS branch %s (least used: %s)\n", best.Path, formatBytes(best.Used))
+	} else {
+		fmt.Printf("Placing rip on MergerFS branch %s (most free space: %s)\n", best.Path, formatBytes(best.Free))
+	}
+	return best.Path, nil
+}
+
+// existingPathBranch returns the first branch holding the deepest existing part of libraryDir,
+// such as the show folder for a new season, and that part of the path.
+// Returns an empty branch if no branch has any of it.
+func existingPathBranch(branches []branchSpace, libraryDir string) (branch, existing string) {
+	rel, err := filepath.Rel(AppConfig.StoragePath, libraryDir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", ""
+	}
+	for dir := rel; dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		for _, b := range branches {
+			if info, err := os.Stat(filepath.Join(b.Path, dir)); err == nil && info.IsDir() {
+				return b.Path, dir
+			}
+		}
+	}
+	return "", ""
+}
+
+// readBranchSpace reads the free and used space of a branch.
+func readBranchSpace(path string) (branchSpace, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return branchSpace{}, fmt.Errorf("could not stat MergerFS branch %s: %v", path, err)
+	}
+	return branchSpace{
+		Path: path,
+		Free: stat.Bavail * uint64(stat.Bsize),
+		Used: (stat.Blocks - stat.Bfree) * uint64(stat.Bsize),
+	}, nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChooseBranch(t *testing.T) {
+	const gb = 1 << 30
+	root := t.TempDir()
+	disk := func(name string) string { return filepath.Join(root, name) }
+	// disk1 has the show folder, disk2 the season folder of another show, disk4 is nearly full
+	// but has the season folder too
+	for _, dir := range []string{"disk1/TV/The Office", "disk2/TV/Parks and Recreation/Season 01", "disk3", "disk4/TV/Parks and Recreation/Season 01"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	space := map[string]branchSpace{
+		disk("disk1"): {Free: 100 * gb, Used: 900 * gb},
+		disk("disk2"): {Free: 400 * gb, Used: 1600 * gb},
+		disk("disk3"): {Free: 300 * gb, Used: 200 * gb},
+		disk("disk4"): {Free: 4 * gb, Used: 10 * gb},
+	}
+	t.Cleanup(func() { branchSpaceOf = readBranchSpace })
+	branchSpaceOf = func(path string) (branchSpace, error) {
+		s, ok := space[path]
+		if !ok {
+			return branchSpace{}, errors.New("could not stat MergerFS branch " + path)
+		}
+		s.Path = path
+		return s, nil
+	}
+	all := []string{disk("disk4"), disk("disk1"), disk("disk2"), disk("disk3"), disk("gone")}
+
+	tests := []struct {
+		name       string
+		policy     string
+		branches   []string
+		libraryDir string
+		want       string // Branch, or part of the error
+	}{
+		{"mfs picks the most free space", placementMostFree, all, "Movies/Heat", "disk2"},
+		{"lus picks the least used space", placementLeastUsed, all, "Movies/Heat", "disk3"},
+		{"lus skips branches under 5 GB free", placementLeastUsed, []string{disk("disk4"), disk("disk1")}, "Movies/Heat", "disk1"},
+		{"epff picks the branch with the show folder", placementExistingPath, all, "TV/The Office/Season 02", "disk1"},
+		{"epff prefers the deepest existing folder", placementExistingPath, all, "TV/Parks and Recreation/Season 01", "disk2"},
+		{"epff skips branches under 5 GB free", placementExistingPath, []string{disk("disk4"), disk("disk1")}, "TV/Parks and Recreation/Season 01", "disk1"},
+		{"epff keeps a new show with the other shows", placementExistingPath, all, "TV/Community/Season 01", "disk1"},
+		{"epff falls back to mfs for new folders", placementExistingPath, all, "Movies/Heat", "disk2"},
+		{"every branch under 5 GB free", placementMostFree, []string{disk("disk4"), disk("gone")}, "Movies/Heat", "no MergerFS branch has at least 5 GB free"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AppConfig = defaultConfig()
+			AppConfig.StoragePath = "/plex/storage"
+			AppConfig.PlacementPolicy = tt.policy
+			branch, err := chooseBranch(tt.branches, filepath.Join(AppConfig.StoragePath, tt.libraryDir))
+			got := strings.TrimPrefix(branch, root+string(filepath.Separator))
+			if err != nil {
+				got = err.Error()
+			}
+			if !strings.Contains(got, tt.want) || (err == nil && got != tt.want) {
+				t.Errorf("chooseBranch = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
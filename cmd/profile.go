@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// profileEnv is the environment variable that selects a profile when --profile is not given.
+const profileEnv = configEnvPrefix + "PROFILE"
+
+// ProfileConfig holds the settings a profile can change, for keeping separate libraries
+// (movies, kids, 4K, anime, ...) on their own storage with their own naming and rules.
+// Settings a profile leaves out keep the values from the top level of the config file.
+type ProfileConfig struct {
+	StoragePath string          `yaml:"storage_path"`
+	StagingPath string          `yaml:"staging_path"`
+	Movie       MovieConfig     `yaml:"movie"`
+	TV          TVConfig        `yaml:"tv"`
+	FileBot     FileBotConfig   `yaml:"filebot"`
+	Naming      NamingConfig    `yaml:"naming"`
+	Paths       PathsConfig     `yaml:"paths"`
+	Transcode   TranscodeConfig `yaml:"transcode"`
+	Hooks       HooksConfig     `yaml:"hooks"`
+}
+
+// selectedProfile returns the profile to use: --profile, else RIP_PROFILE, else default_profile.
+// Returns an empty name when no profile is selected.
+func (c *Config) selectedProfile() string {
+	if profileFlag != "" {
+		return profileFlag
+	}
+	if name := os.Getenv(profileEnv); name != "" {
+		return name
+	}
+	return c.DefaultProfile
+}
+
+// applyProfile sets the values of a profile over the configuration, and records them as
+// coming from the profile.
+//
+// Parameters:
+//
+//	name - the name of the profile under profiles in the config file
+//
+// Returns an error if there is no such profile or it has keys a profile cannot set.
+func (c *Config) applyProfile(name string) error {
+	node, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return fmt.Errorf("unknown profile %q: the config file has no profiles", name)
+		}
+		return fmt.Errorf("unknown profile %q (profiles: %s)", name, c.profileNames())
+	}
+
+	profile := ProfileConfig{
+		StoragePath: c.StoragePath,
+		StagingPath: c.StagingPath,
+		Movie:       c.Movie,
+		TV:          c.TV,
+		FileBot:     c.FileBot,
+		Naming:      c.Naming,
+		Paths:       c.Paths,
+		Transcode:   c.Transcode,
+		Hooks:       c.Hooks,
+	}
+	// Decode through the encoder so keys a profile cannot set are reported, as in the rest of the file
+	content, err := yaml.Marshal(&node)
+	if err != nil {
+		return fmt.Errorf("profiles.%s: %v", name, err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(&profile); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("profiles.%s: %v", name, err)
+	}
+
+	c.StoragePath = expandHome(profile.StoragePath)
+	c.StagingPath = expandHome(profile.StagingPath)
+	c.Movie = profile.Movie
+	c.TV = profile.TV
+	c.FileBot = profile.FileBot
+	c.Naming = profile.Naming
+	c.Paths = profile.Paths
+	c.Transcode = profile.Transcode
+	c.Hooks = profile.Hooks
+	c.profile = name
+
+	keys := map[string]string{}
+	markFileKeys(&node, "", keys)
+	c.sources = maps.Clone(c.sources)
+	if c.sources == nil {
+		c.sources = map[string]string{}
+	}
+	for key := range keys {
+		c.sources[key] = configSourceProfile
+	}
+	return nil
+}
+
+// checkProfiles applies every profile to a copy of the configuration and validates the result,
+// so a broken profile is reported even when it is not the one in use.
+// Problems the top level already has are left out, so they are not reported once per profile.
+// Returns the problems found, each starting with the profile's key, e.g. "profiles.kids: ...".
+func (c *Config) checkProfiles() []string {
+	var problems, shared []string
+	if err := c.Validate(); err != nil {
+		shared = strings.Split(err.Error(), "\n")
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Profiles)) {
+		profiled := *c
+		profiled.DefaultProfile = ""
+		profiled.profileProblems = nil
+		if err := profiled.applyProfile(name); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if err := profiled.Validate(); err != nil {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				if slices.Contains(shared, line) {
+					continue
+				}
+				problems = append(problems, fmt.Sprintf("profiles.%s: %s", name, line))
+			}
+		}
+	}
+	return problems
+}
+
+// profileNames returns the names of the profiles, sorted and separated by commas.
+func (c *Config) profileNames() string {
+	return strings.Join(slices.Sorted(maps.Keys(c.Profiles)), ", ")
+}
+
+// Profile returns the name of the profile in use, or an empty string when none is.
+func (c *Config) Profile() string {
+	return c.profile
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+const profileTestFile = `version: 1
+storage_path: /srv/movies
+movie:
+  min_length: 50m
+naming:
+  preset: plex
+profiles:
+  kids:
+    storage_path: /srv/kids
+    movie:
+      min_length: 30m
+  4k:
+    storage_path: /srv/4k
+    transcode:
+      preset: "H.265 MKV 2160p60"
+`
+
+func TestLoadConfigProfiles(t *testing.T) {
+	type value struct{ key, want, source string }
+	tests := []struct {
+		name    string
+		env     map[string]string
+		set     []string
+		profile string // --profile
+		want    []value
+	}{
+		{"no profile", nil, nil, "", []value{
+			{"storage_path", "/srv/movies", configSourceFile},
+			{"movie.min_length", "50m", configSourceFile},
+		}},
+		{"profile over the file", nil, nil, "kids", []value{
+			{"storage_path", "/srv/kids", configSourceProfile},
+			{"movie.min_length", "30m", configSourceProfile},
+			{"naming.preset", "plex", configSourceFile},
+			{"tv.min_episode_length", "10m", configSourceDefault},
+			{"transcode.preset", "", configSourceDefault},
+		}},
+		{"RIP_PROFILE", map[string]string{"RIP_PROFILE": "4k"}, nil, "", []value{
+			{"storage_path", "/srv/4k", configSourceProfile},
+			{"transcode.preset", "H.265 MKV 2160p60", configSourceProfile},
+			{"movie.min_length", "50m", configSourceFile},
+		}},
+		{"--profile over RIP_PROFILE", map[string]string{"RIP_PROFILE": "4k"}, nil, "kids", []value{
+			{"storage_path", "/srv/kids", configSourceProfile},
+			{"transcode.preset", "", configSourceDefault},
+		}},
+		{"default_profile set with --set", nil, []string{"default_profile=kids"}, "", []value{
+			{"storage_path", "/srv/kids", configSourceProfile},
+		}},
+		{"env over the profile", map[string]string{"RIP_STORAGE_PATH": "/srv/env"}, nil, "kids", []value{
+			{"storage_path", "/srv/env", configSourceEnv},
+			{"movie.min_length", "30m", configSourceProfile},
+		}},
+		{"--set over env and the profile", map[string]string{"RIP_MOVIE_MIN_LENGTH": "40m"}, []string{"movie.min_length=20m"}, "kids", []value{
+			{"movie.min_length", "20m", configSourceFlag},
+			{"storage_path", "/srv/kids", configSourceProfile},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadTestConfig(t, profileTestFile, tt.env, "", tt.set, tt.profile)
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			for _, v := range tt.want {
+				if got, _ := config.Get(v.key); got != v.want || config.Source(v.key) != v.source {
+					t.Errorf("%s = %q from %s, want %q from %s", v.key, got, config.Source(v.key), v.want, v.source)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigProfileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		profile string
+		want    string
+	}{
+		{"unknown profile", profileTestFile, "anime", `unknown profile "anime" (profiles: 4k, kids)`},
+		{"no profiles", "version: 1\n", "kids", `unknown profile "kids": the config file has no profiles`},
+		{"key a profile cannot set", profileTestFile + "  broken:\n    device: /dev/sr1\n", "", "profiles.broken: yaml: unmarshal errors"},
+		{"invalid value in a profile not in use", profileTestFile + "  broken:\n    movie:\n      min_length: -5m\n", "kids",
+			"profiles.broken: movie.min_length must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTestConfig(t, tt.file, nil, "", nil, tt.profile)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfig error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressPrefix marks progress events written by the "json" progress mode.
+// Consumers reading rip output (such as the web daemon) look for lines starting with it.
+const progressPrefix = "PROGRESS "
+
+// ProgressEvent is a single progress update from a running makemkvcon operation.
+// It is built from the PRGT (total operation), PRGC (current operation) and PRGV (values) lines
+// that makemkvcon prints when run with --progress=-same in robot mode.
+type ProgressEvent struct {
+	Operation        string        `json:"operation"`        // Total operation name (PRGT), e.g. "Saving to MKV file"
+	SubOperation     string        `json:"subOperation"`     // Current operation name (PRGC)
+	Percent          float64       `json:"percent"`          // Overall progress, 0-100
+	OperationPercent float64       `json:"operationPercent"` // Progress of the current operation, 0-100
+	Elapsed          time.Duration `json:"elapsed"`          // Time since the total operation started
+	ETA              time.Duration `json:"eta"`              // Estimated time remaining, zero when unknown
+}
+
+// ProgressListener receives progress events as they are parsed.
+type ProgressListener func(ProgressEvent)
+
+// progressTracker turns makemkvcon progress lines into ProgressEvents.
+type progressTracker struct {
+	listeners    []ProgressListener
+	operation    string
+	subOperation string
+	started      time.Time
+	now          func() time.Time
+}
+
+// newProgressTracker returns a tracker that sends every event to the given listeners.
+func newProgressTracker(listeners ...ProgressListener) *progressTracker {
+	return &progressTracker{listeners: listeners, now: time.Now}
+}
+
+// ParseLine handles a single makemkvcon output line.
+// Returns true if the line was a progress line (PRGT, PRGC or PRGV).
+func (t *progressTracker) ParseLine(line string) bool {
+	kind, fields, ok := splitRobotLine(line)
+	if !ok {
+		return false
+	}
+
+	switch kind {
+	case "PRGT":
+		// PRGT:code,id,"name" - a new total operation starts
+		if len(fields) >= 3 {
+			t.operation = fields[2]
+			t.subOperation = ""
+			t.started = t.now()
+		}
+		return true
+
+	case "PRGC":
+		// PRGC:code,id,"name" - a new sub-operation starts
+		if len(fields) >= 3 {
+			t.subOperation = fields[2]
+		}
+		return true
+
+	case "PRGV":
+		// PRGV:current,total,max - current and total progress, both out of max
+		if len(fields) < 3 {
+			return true
+		}
+		current, err1 := strconv.Atoi(fields[0])
+		total, err2 := strconv.Atoi(fields[1])
+		maxValue, err3 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil || err3 != nil || maxValue <= 0 {
+			return true
+		}
+		if t.started.IsZero() {
+			t.started = t.now()
+		}
+
+		event := ProgressEvent{
+			Operation:        t.operation,
+			SubOperation:     t.subOperation,
+			Percent:          float64(total) * 100 / float64(maxValue),
+			OperationPercent: float64(current) * 100 / float64(maxValue),
+			Elapsed:          t.now().Sub(t.started),
+		}
+		// Estimate the remaining time from the average rate so far
+		if event.Percent > 0 && event.Percent < 100 {
+			event.ETA = time.Duration(float64(event.Elapsed) * (100 - event.Percent) / event.Percent)
+		}
+
+		for _, listener := range t.listeners {
+			listener(event)
+		}
+		return true
+	}
+
+	return false
+}
+
+// runMakeMKVWithProgress runs makemkvcon in robot mode with progress output enabled,
+// sending progress events to listener while the command runs.
+// The text of every non-progress message is collected and returned as the command output.
+//
+// Parameters:
+//
+//	args - the makemkvcon arguments after the robot and progress flags (e.g. "mkv", "disc:0", "all", "/out")
+//	listener - receives progress events; may be nil
+//
+// Returns the collected output and an error if makemkvcon fails.
+func runMakeMKVWithProgress(args []string, listener ProgressListener) (string, error) {
+	var listeners []ProgressListener
+	if listener != nil {
+		listeners = append(listeners, listener)
+	}
+	tracker := newProgressTracker(listeners...)
+
+	var output strings.Builder
+	fullArgs := append([]string{"-r", "--progress=-same"}, args...)
+	err := runner.Stream("makemkvcon", fullArgs, func(line string) {
+		if tracker.ParseLine(line) {
+			return
+		}
+		// MSG lines carry the human readable message as their fourth field
+		if kind, fields, ok := splitRobotLine(line); ok && kind == "MSG" && len(fields) >= 4 {
+			line = fields[3]
+		}
+		output.WriteString(line)
+		output.WriteString("\n")
+	})
+	return output.String(), err
+}
+
+// newProgressListener returns the listener for a --progress mode.
+// Supported modes:
+//
+//	bar  - an updating progress bar on stderr (falls back to "log" when stderr is not a terminal)
+//	log  - a plain progress line every 5 percent
+//	json - "PROGRESS {...}" JSON lines on stdout, for other programs to consume
+//	none - no progress output
+//
+// Returns an error for unknown modes.
+func newProgressListener(mode string) (ProgressListener, error) {
+	switch mode {
+	case "bar":
+		if !isTerminal(os.Stderr) {
+			return logProgressListener(os.Stdout), nil
+		}
+		return barProgressListener(os.Stderr), nil
+	case "log":
+		return logProgressListener(os.Stdout), nil
+	case "json":
+		return jsonProgressListener(os.Stdout), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown progress mode %q (use bar, log, json or none)", mode)
+	}
+}
+
+// barProgressListener draws a single-line progress bar that is redrawn in place.
+func barProgressListener(w io.Writer) ProgressListener {
+	const width = 30
+	var last time.Time
+	return func(e ProgressEvent) {
+		// Redraw at most a few times per second, but always draw the final state
+		if time.Since(last) < 200*time.Millisecond && e.Percent < 100 {
+			return
+		}
+		last = time.Now()
+
+		filled := int(e.Percent / 100 * width)
+		if filled > width {
+			filled = width
+		}
+		bar := strings.Repeat("#", filled) + strings.Repeat("-", width-filled)
+		fmt.Fprintf(w, "\r[%s] %5.1f%% %-40s ETA %s", bar, e.Percent, truncate(progressLabel(e), 40), formatETA(e.ETA))
+		if e.Percent >= 100 {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// logProgressListener prints a progress line whenever the overall progress
+// crosses another 5 percent or the operation changes.
+func logProgressListener(w io.Writer) ProgressListener {
+	lastStep := -1
+	lastOperation := ""
+	return func(e ProgressEvent) {
+		step := int(e.Percent) / 5
+		if step == lastStep && e.Operation == lastOperation {
+			return
+		}
+		lastStep = step
+		lastOperation = e.Operation
+		fmt.Fprintf(w, "Progress: %5.1f%% %s, ETA %s\n", e.Percent, progressLabel(e), formatETA(e.ETA))
+	}
+}
+
+// jsonProgressListener writes events as progressPrefix JSON lines.
+// An event is written whenever the overall progress moves by a tenth of a percent or the operation changes.
+func jsonProgressListener(w io.Writer) ProgressListener {
+	var mu sync.Mutex
+	lastPercent := -1.0
+	lastLabel := ""
+	return func(e ProgressEvent) {
+		label := progressLabel(e)
+		if e.Percent-lastPercent < 0.1 && label == lastLabel {
+			return
+		}
+		lastPercent = e.Percent
+		lastLabel = label
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "%s%s\n", progressPrefix, data)
+	}
+}
+
+// parseProgressLine decodes a line written by the "json" progress mode.
+// Returns ok=false if the line is not a progress line.
+func parseProgressLine(line string) (ProgressEvent, bool) {
+	var e ProgressEvent
+	data, found := strings.CutPrefix(strings.TrimSpace(line), progressPrefix)
+	if !found {
+		return e, false
+	}
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return e, false
+	}
+	return e, true
+}
+
+// progressLabel describes the current operation, e.g. "Saving to MKV file (Analyzing seamless segments)".
+func progressLabel(e ProgressEvent) string {
+	if e.SubOperation != "" && e.SubOperation != e.Operation {
+		return fmt.Sprintf("%s (%s)", e.Operation, e.SubOperation)
+	}
+	return e.Operation
+}
+
+// formatETA formats a remaining time for display, or "--:--" when it is unknown.
+func formatETA(d time.Duration) string {
+	if d <= 0 {
+		return "--:--"
+	}
+	d = d.Round(time.Second)
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	s := int(d.Seconds()) % 60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// isTerminal reports whether f is connected to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
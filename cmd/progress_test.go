@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressTracker(t *testing.T) {
+	var events []ProgressEvent
+	tracker := newProgressTracker(func(e ProgressEvent) { events = append(events, e) })
+	now := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	lines := []struct {
+		line     string
+		progress bool
+		advance  time.Duration // Clock change before the line
+	}{
+		{`MSG:5014,0,1,"Saving 1 titles into directory /out","",""`, false, 0},
+		{`PRGT:5018,0,"Saving to MKV file"`, true, 0},
+		{`PRGC:5017,0,"Analyzing seamless segments"`, true, 0},
+		{`PRGV:0,0,65536`, true, 0},
+		{`PRGC:5018,0,"Saving to MKV file"`, true, 0},
+		{`PRGV:32768,16384,65536`, true, 10 * time.Minute},
+		{`PRGV:65536,65536,65536`, true, 30 * time.Minute},
+		{`PRGV:1,2`, true, 0},
+		{`PRGV:1,2,0`, true, 0},
+		{`TINFO:0,9,0,"1:45:43"`, false, 0},
+	}
+	for _, l := range lines {
+		now = now.Add(l.advance)
+		if got := tracker.ParseLine(l.line); got != l.progress {
+			t.Errorf("ParseLine(%q) = %v, want %v", l.line, got, l.progress)
+		}
+	}
+
+	want := []ProgressEvent{
+		{Operation: "Saving to MKV file", SubOperation: "Analyzing seamless segments"},
+		{Operation: "Saving to MKV file", SubOperation: "Saving to MKV file", Percent: 25, OperationPercent: 50, Elapsed: 10 * time.Minute, ETA: 30 * time.Minute},
+		{Operation: "Saving to MKV file", SubOperation: "Saving to MKV file", Percent: 100, OperationPercent: 100, Elapsed: 40 * time.Minute},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+	if got := progressLabel(events[1]); got != "Saving to MKV file" {
+		t.Errorf("progressLabel = %q, want the operation once", got)
+	}
+	if got := progressLabel(events[0]); got != "Saving to MKV file (Analyzing seamless segments)" {
+		t.Errorf("progressLabel = %q", got)
+	}
+}
+
+func TestJSONProgressRoundTrip(t *testing.T) {
+	var out strings.Builder
+	listener := jsonProgressListener(&out)
+	events := []ProgressEvent{
+		{Operation: "Saving to MKV file", Percent: 12.5, OperationPercent: 40, Elapsed: 5 * time.Minute, ETA: 35 * time.Minute},
+		{Operation: "Saving to MKV file", Percent: 12.55}, // Less than a tenth of a percent on: not written
+		{Operation: "Saving to MKV file", SubOperation: "Processing AV frames", Percent: 12.55},
+		{Operation: "Saving to MKV file", Percent: 100, OperationPercent: 100, Elapsed: 40 * time.Minute},
+	}
+	for _, e := range events {
+		listener(e)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []ProgressEvent{events[0], events[2], events[3]}
+	if len(lines) != len(want) {
+		t.Fatalf("wrote %d lines, want %d:\n%s", len(lines), len(want), out.String())
+	}
+	for i, line := range lines {
+		e, ok := parseProgressLine(line)
+		if !ok {
+			t.Errorf("parseProgressLine(%q) failed", line)
+			continue
+		}
+		if e != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+
+	for _, line := range []string{"Progress:  12.5% Saving to MKV file, ETA 35:00", "PROGRESS {not json", ""} {
+		if _, ok := parseProgressLine(line); ok {
+			t.Errorf("parseProgressLine(%q) = ok, want not a progress line", line)
+		}
+	}
+}
+
+func TestFormatETA(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "--:--"},
+		{-time.Second, "--:--"},
+		{95 * time.Second, "01:35"},
+		{time.Hour + 2*time.Minute + 3*time.Second, "1:02:03"},
+	}
+	for _, tt := range tests {
+		if got := formatETA(tt.d); got != tt.want {
+			t.Errorf("formatETA(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// queueFileName is the name of the watch queue file in the config directory.
+const queueFileName = "watch-queue.json"
+
+// queueEntry pre-assigns how the next inserted disc is ripped by rip watch.
+// It uses the same fields as a web rip request; an empty Device matches any drive.
+type queueEntry struct {
+	ripRequest
+	AddedAt time.Time `json:"addedAt"`
+}
+
+// queueCmd represents the `watch queue` command group for managing pre-assigned discs.
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Pre-assign a category or show to the next inserted disc",
+	Long: `Manage the queue of pre-assigned discs used by rip watch.
+
+When rip watch detects a disc, it takes the first queued entry that matches the drive
+and the kind of disc it detected (movie or TV) and rips the disc with those settings.
+Entries are used once, in the order they were added.`,
+}
+
+// queueAddCmd adds an entry to the watch queue.
+var queueAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a disc to the watch queue",
+	Long: `Add a disc to the watch queue.
+
+Movies need a category:  rip watch queue add --category Comedy [--movie "Anchorman"]
+TV discs need a show:    rip watch queue add --show "The Office" --season-disc 1-2`,
+	Args: cobra.NoArgs,
+	Run:  runQueueAdd,
+}
+
+// queueListCmd lists the watch queue.
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the watch queue",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := loadQueue()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("The watch queue is empty.")
+			return
+		}
+		for i, e := range entries {
+			fmt.Printf("%d. %s\n", i+1, e.describe())
+		}
+	},
+}
+
+// queueClearCmd empties the watch queue.
+var queueClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all entries from the watch queue",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := updateQueue(func([]queueEntry) ([]queueEntry, error) { return nil, nil })
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		fmt.Println("Watch queue cleared.")
+	},
+}
+
+// runQueueAdd validates the flags and appends the entry to the queue.
+func runQueueAdd(cmd *cobra.Command, args []string) {
+	var e queueEntry
+	e.Device, _ = cmd.Flags().GetString("device")
+	e.Category, _ = cmd.Flags().GetString("category")
+	e.Movie, _ = cmd.Flags().GetString("movie")
+	e.Show, _ = cmd.Flags().GetString("show")
+	e.SeasonDisc, _ = cmd.Flags().GetString("season-disc")
+	e.TMDBID, _ = cmd.Flags().GetInt("tmdb-id")
+	e.AddedAt = time.Now()
+
+	if e.Show != "" || e.SeasonDisc != "" {
+		e.Type = "tv"
+	} else {
+		e.Type = "dvd"
+	}
+
+	// Check the entry the same way the rip will be started, so mistakes show up now
+	probe := e.ripRequest
+	if probe.Device == "" {
+		probe.Device = AppConfig.Device
+	}
+	if _, err := ripJobArgs(probe); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	err := updateQueue(func(entries []queueEntry) ([]queueEntry, error) {
+		return append(entries, e), nil
+	})
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	fmt.Printf("Queued: %s\n", e.describe())
+}
+
+// describe returns a one-line description of the entry.
+func (e queueEntry) describe() string {
+	var what string
+	if e.Type == "tv" {
+		what = fmt.Sprintf("TV %q disc %s", e.Show, e.SeasonDisc)
+	} else if e.Movie != "" {
+		what = fmt.Sprintf("movie %q in %s", e.Movie, e.Category)
+	} else {
+		what = fmt.Sprintf("movie in %s", e.Category)
+	}
+	if e.TMDBID > 0 {
+		what += fmt.Sprintf(" (tmdb %d)", e.TMDBID)
+	}
+	drive := "any drive"
+	if e.Device != "" {
+		drive = e.Device
+	}
+	return fmt.Sprintf("%s, %s, added %s", what, drive, e.AddedAt.Format("2006-01-02 15:04"))
+}
+
+// queuePath returns the path of the watch queue file.
+func queuePath() (string, error) {
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, queueFileName), nil
+}
+
+// loadQueue reads the watch queue. A missing queue file is an empty queue.
+// Changes to the queue go through updateQueue, which holds the queue lock.
+func loadQueue() ([]queueEntry, error) {
+	path, err := queuePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading watch queue: %v", err)
+	}
+	var entries []queueEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("error parsing watch queue %s: %v", path, err)
+	}
+	return entries, nil
+}
+
+// updateQueue reads the watch queue, changes it with fn and writes it back, holding an exclusive
+// lock for the whole time so rips started by rip watch on several drives at once and rip watch
+// queue commands never take the same entry or lose one. The lock is on a separate lock file,
+// since saveQueue replaces the queue file. Nothing is written if fn returns an error.
+func updateQueue(fn func(entries []queueEntry) ([]queueEntry, error)) error {
+	path, err := queuePath()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening watch queue lock: %v", err)
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("error locking watch queue: %v", err)
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	entries, err := loadQueue()
+	if err != nil {
+		return err
+	}
+	entries, err = fn(entries)
+	if err != nil {
+		return err
+	}
+	return saveQueue(entries)
+}
+
+// saveQueue writes the watch queue through a temporary file, so readers never see a partly
+// written queue. Callers hold the queue lock (see updateQueue).
+func saveQueue(entries []queueEntry) error {
+	path, err := queuePath()
+	if err != nil {
+		return err
+	}
+	if entries == nil {
+		entries = []queueEntry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding watch queue: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), queueFileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing watch queue: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing watch queue: %v", err)
+	}
+	return nil
+}
+
+// takeQueueEntry removes and returns the first queued entry for a drive and disc kind.
+// kind is "dvd" or "tv"; an empty kind (the disc could not be classified) matches either.
+// It returns nil if no entry matches.
+func takeQueueEntry(device, kind string) (*queueEntry, error) {
+	var taken *queueEntry
+	err := updateQueue(func(entries []queueEntry) ([]queueEntry, error) {
+		for i, e := range entries {
+			if e.Device != "" && e.Device != device {
+				continue
+			}
+			if kind != "" && e.Type != kind {
+				continue
+			}
+			taken = &e
+			return append(entries[:i], entries[i+1:]...), nil
+		}
+		return entries, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return taken, nil
+}
+
+// init registers the queue commands with the watch command and configures their flags.
+func init() {
+	queueAddCmd.Flags().StringP("device", "d", "", "Only use this entry for discs in this drive (default: any drive)")
+	queueAddCmd.Flags().StringP("category", "c", "", "Category folder for a movie (e.g. Comedy)")
+	queueAddCmd.Flags().StringP("movie", "m", "", "Movie name, instead of discovering it from the disc")
+	queueAddCmd.Flags().String("show", "", "TV show name")
+	queueAddCmd.Flags().String("season-disc", "", "Season and disc of a TV disc (e.g. 1-2)")
+	queueAddCmd.Flags().Int("tmdb-id", 0, "TMDB ID of the movie or show, instead of the best match")
+
+	queueCmd.AddCommand(queueAddCmd, queueListCmd, queueClearCmd)
+	watchCmd.AddCommand(queueCmd)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestWatchHandleTakesQueueEntryOnce(t *testing.T) {
+	env := newRipEnv(t)
+	AppConfig = defaultConfig()
+	env.Fake.On("makemkvcon", "-r", "info").Return(robotInfo("1:45:43", "0:12:00"), nil)
+	env.Fake.On("/usr/local/bin/rip").Return("", nil)
+	err := updateQueue(func(entries []queueEntry) ([]queueEntry, error) {
+		return append(entries, queueEntry{ripRequest: ripRequest{Type: discKindMovie, Category: "Comedy", Movie: "Anchorman"}}), nil
+	})
+	if err != nil {
+		t.Fatalf("updateQueue: %v", err)
+	}
+
+	// Two discs inserted together; without a --category only a queued entry rips a movie
+	w := &watcher{executable: "/usr/local/bin/rip", handled: map[int]bool{}}
+	var wg sync.WaitGroup
+	for i, device := range []string{"/dev/sr0", "/dev/sr1"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.handle(DriveInfo{Index: i, Device: device, DiscName: "ANCHORMAN"})
+		}()
+	}
+	wg.Wait()
+
+	if got := env.Fake.Called("/usr/local/bin/rip"); got != 1 {
+		t.Errorf("rip ran %d times, want once for the one queued entry", got)
+	}
+	if call := findCall(env.Fake, "/usr/local/bin/rip"); call != nil && !slices.Contains(call, "Comedy") {
+		t.Errorf("rip = %q, want the queued category", call)
+	}
+	if entries, err := loadQueue(); err != nil || len(entries) != 0 {
+		t.Errorf("queue after the rips = %v (%v), want empty", entries, err)
+	}
+}
+
+func TestUpdateQueueKeepsConcurrentAdds(t *testing.T) {
+	newRipEnv(t)
+	const adds = 20
+	var wg sync.WaitGroup
+	for i := range adds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := updateQueue(func(entries []queueEntry) ([]queueEntry, error) {
+				return append(entries, queueEntry{ripRequest: ripRequest{Type: discKindMovie, Category: fmt.Sprint(i)}}), nil
+			})
+			if err != nil {
+				t.Errorf("updateQueue: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := loadQueue()
+	if err != nil {
+		t.Fatalf("loadQueue: %v", err)
+	}
+	if len(entries) != adds {
+		t.Errorf("queue has %d entries, want %d", len(entries), adds)
+	}
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// errRipCancelled is the error of a rip stopped with Ctrl-C or SIGTERM.
+var errRipCancelled = errors.New("rip cancelled")
+
+// signalContext returns a context that is cancelled by the first Ctrl-C or SIGTERM.
+// The signal is only caught once: a second Ctrl-C quits immediately, without cleaning up.
+// The returned stop function must be called when the rip is done.
+func signalContext() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			fmt.Printf("\nReceived %v: cancelling the rip and cleaning up (press Ctrl-C again to quit immediately)...\n", sig)
+			cancel()
+		case <-done:
+		}
+	}()
+	return ctx, func() {
+		close(done)
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// ripRollback finishes a rip run. On success it saves the run in the history; when the run fails
+// or is cancelled it first removes what the run created, so nothing partial is left behind:
+// the staging directory with half-ripped files and any files and directories already moved into
+// the library. With --eject-on-failure the disc is ejected as well.
+//
+// While the rip runs, every external command is bound to the rip's context, so cancelling the
+// rip stops a running makemkvcon.
+type ripRollback struct {
+	ctx     context.Context
+	source  *DiscSource
+	eject   bool         // Eject the disc when the rip fails
+	hist    *runHistory  // Set once the run history has been started
+	stage   *stagingArea // Set once the staging area has been created
+	restore func()       // Restores the runner that is not bound to ctx
+}
+
+// newRipRollback starts running the rip's external commands under ctx.
+// Finish must be called with the rip's result when the rip is done.
+//
+// Parameters:
+//
+//	ctx - the rip's context, usually from signalContext
+//	source - where the rip reads from
+//	eject - whether to eject the disc when the rip fails
+func newRipRollback(ctx context.Context, source *DiscSource, eject bool) *ripRollback {
+	return &ripRollback{
+		ctx:     ctx,
+		source:  source,
+		eject:   eject,
+		restore: SetRunner(runnerWithContext(runner, ctx)),
+	}
+}
+
+// Finish cleans up after a failed or cancelled rip, saves the run in the history and stops
+// binding commands to the rip's context.
+//
+// Returns err, or errRipCancelled if the rip was cancelled.
+func (r *ripRollback) Finish(err error) error {
+	if err != nil && r.ctx.Err() != nil {
+		err = errRipCancelled
+	}
+	if err != nil {
+		fmt.Printf("Rip failed: %v\n", err)
+		fmt.Println("Removing the files this rip created...")
+		r.stage.Rollback()
+	}
+
+	// The history restores the runner it wrapped, so it has to finish before the runner is unbound
+	r.hist.Finish(err)
+	r.restore()
+
+	if err != nil && r.eject && r.source.IsDevice() {
+		if ejectErr := ejectDisc(r.source.Device); ejectErr != nil {
+			fmt.Printf("Warning: Could not eject disc: %v\n", ejectErr)
+		}
+	}
+	return err
+}
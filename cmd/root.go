@@ -3,9 +3,9 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 
 	"github.com/spf13/cobra"
 )
@@ -20,6 +20,7 @@ This tool is ideal for creating a well-structured media library compatible with
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: loadAppConfig,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -33,11 +34,7 @@ func Execute() {
 		}
 	}
 
-	// Load configuration
-	AppConfig = LoadConfig()
-
-	err := rootCmd.Execute()
-	if err != nil {
+	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
@@ -45,19 +42,67 @@ func Execute() {
 // AppConfig holds the global application configuration
 var AppConfig *Config
 
-// init initializes the root command and configures global flags.
-// Currently, no persistent flags are configured, but this function
-// serves as a place for future global configuration.
-func init() {
-	// Define global flags and configuration settings
-	// Cobra supports persistent flags, which, if defined here,
-	// will be global for your application.
+// configErr is the error loading the config file, if it is invalid. AppConfig then holds the defaults.
+var configErr error
+
+// Global flags that choose and override the configuration (see LoadConfig).
+var (
+	cfgFile     string   // --config: the config file to use instead of ~/.rip.yaml
+	storageFlag string   // --storage: overrides storage_path
+	setFlags    []string // --set key=value: overrides any config key
+	profileFlag string   // --profile: the profile to use instead of default_profile
+)
+
+// loadAppConfig loads the configuration once the global flags are parsed, before any command runs.
+// An invalid configuration stops the command, unless the command (or the command it belongs to)
+// is marked with allowInvalidConfig, so that rip config can still check and fix it.
+// Help and shell completion always run.
+func loadAppConfig(cmd *cobra.Command, args []string) error {
+	config, err := LoadConfig()
+	if err != nil {
+		configErr = err
+		config = defaultConfig()
+	}
+	AppConfig = config
+	if configErr == nil {
+		return nil
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[allowInvalidConfig] != "" || c.Name() == "help" || c.Name() == cobra.ShellCompRequestCmd {
+			return nil
+		}
+	}
+	cmd.SilenceUsage = true
+	return fmt.Errorf("%v\nRun rip config validate to check the configuration", configErr)
+}
 
-	// Example: rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.rip.yaml)")
+// configFlagArgs returns the global config flags this rip was started with, for the rips that
+// rip watch and rip web run as subprocesses, so they use the same configuration.
+// RIP_* environment variables are inherited by the subprocesses and need no flags.
+func configFlagArgs() []string {
+	var args []string
+	if cfgFile != "" {
+		args = append(args, "--config", cfgFile)
+	}
+	if storageFlag != "" {
+		args = append(args, "--storage", storageFlag)
+	}
+	for _, setting := range setFlags {
+		args = append(args, "--set", setting)
+	}
+	if profileFlag != "" {
+		args = append(args, "--profile", profileFlag)
+	}
+	return args
+}
 
-	// Cobra also supports local flags, which will only run
-	// when this action is called directly.
-	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+// init initializes the root command and configures global flags.
+// The global flags override the config file for every command; see LoadConfig for the precedence.
+func init() {
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file (default: $RIP_CONFIG, else ~/.rip.yaml)")
+	rootCmd.PersistentFlags().StringVar(&storageFlag, "storage", "", "Storage path for ripped media, overriding storage_path")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Profile to use (default: $RIP_PROFILE, else default_profile)")
+	rootCmd.PersistentFlags().StringArrayVar(&setFlags, "set", nil, "Override a config key for this run, e.g. --set limits.write_jobs=1 (repeatable)")
 }
 
 // ejectDisc ejects the disc from the specified device using the eject command.
@@ -69,8 +114,8 @@ func init() {
 //
 // Returns an error if the eject command fails or the device is not accessible.
 func ejectDisc(devicePath string) error {
-	ejectCmd := exec.Command("eject", devicePath)
-	return ejectCmd.Run()
+	_, err := runner.Run("eject", devicePath)
+	return err
 }
 
 // extractDevicePath converts a disc specification to a device path.
@@ -90,13 +135,29 @@ func extractDevicePath(driveSpec string) string {
 	return fmt.Sprintf("/dev/sr%s", driveIndex)
 }
 
-func renameMKVFile(dir, filename string) error {
+// renameMKVFile names the MKV files MakeMKV just wrote to dir filename.mkv; should there be
+// more than one, the others are numbered (filename1.mkv, ...).
+//
+// Parameters:
+//
+//	dir - the directory MakeMKV wrote to
+//	filename - the new name without the .mkv extension
+//	existing - files already in dir before the rip (e.g. earlier editions); they are left alone
+//
+// Returns an error if there is no new MKV file or a file cannot be renamed.
+func renameMKVFile(dir, filename string, existing []string) error {
 	// Find all MKV files in the directory
 	pattern := filepath.Join(dir, "*.mkv")
-	files, err := filepath.Glob(pattern)
+	all, err := filepath.Glob(pattern)
 	if err != nil {
 		return fmt.Errorf("error globbing MKV files: %v", err)
 	}
+	var files []string
+	for _, f := range all {
+		if !slices.Contains(existing, f) {
+			files = append(files, f)
+		}
+	}
 
 	// Warn if more than one file found
 	if len(files) > 1 {
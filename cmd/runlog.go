@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// historyFileName is the JSON Lines file in the config directory holding one HistoryRecord per rip.
+const historyFileName = "history.jsonl"
+
+// HistoryRecord is the saved record of one rip dvd or rip tv run.
+type HistoryRecord struct {
+	ID         string          `json:"id"`
+	Kind       string          `json:"kind"` // "dvd" or "tv"
+	Args       []string        `json:"args"` // Command line arguments of the run
+	Source     string          `json:"source,omitempty"`
+	Device     string          `json:"device,omitempty"`
+	DiscLabel  string          `json:"discLabel,omitempty"`
+	Titles     []HistoryTitle  `json:"titles,omitempty"` // Titles selected for ripping
+	Query      string          `json:"query,omitempty"`  // Name the metadata lookup searched for
+	Match      *MetadataResult `json:"match,omitempty"`  // Chosen metadata match, if any
+	OutputDir  string          `json:"outputDir,omitempty"`
+	Files      []string        `json:"files,omitempty"` // Final media files
+	Status     string          `json:"status"`          // "success" or "failed"
+	Error      string          `json:"error,omitempty"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	LogFile    string          `json:"logFile,omitempty"` // Output of every external command run
+}
+
+// HistoryTitle is a disc title selected for ripping.
+type HistoryTitle struct {
+	ID       int    `json:"id"`
+	Duration string `json:"duration"`
+	Size     string `json:"size,omitempty"`
+	Reason   string `json:"reason,omitempty"` // Why the title was chosen, for movie rips
+}
+
+// runHistory collects the history record of the current run and captures the output of every
+// external command in the run's log file. Its methods are safe to call on a nil runHistory,
+// which is what startRunHistory returns when the history cannot be written.
+type runHistory struct {
+	rec     HistoryRecord
+	logFile *os.File
+	restore func()
+}
+
+// startRunHistory starts recording a rip run.
+// From here on every command run through the runner is also written to the run's log file.
+//
+// Parameters:
+//
+//	kind - "dvd" or "tv"
+//	args - the command line arguments, saved with the record
+//	source - where the run reads from
+//	job - the drive job of the run, whose ID the record uses; nil for images and folders
+//
+// Returns the run history; nil (with a warning) if the config directory is not usable.
+func startRunHistory(kind string, args []string, source *DiscSource, job *Job) *runHistory {
+	dir, err := historyDir()
+	if err != nil {
+		fmt.Printf("Warning: This run will not be saved in the history: %v\n", err)
+		return nil
+	}
+	now := time.Now()
+	h := &runHistory{rec: HistoryRecord{
+		ID:        runID(now, kind),
+		Kind:      kind,
+		Args:      args,
+		Source:    source.Spec,
+		StartedAt: now,
+	}}
+	if job != nil {
+		h.rec.ID = job.Info.ID
+	}
+	if source.IsDevice() {
+		h.rec.Device = source.Device
+	}
+
+	path := filepath.Join(dir, h.rec.ID+".log")
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("Warning: Could not create the run log: %v\n", err)
+		return h
+	}
+	h.logFile = f
+	h.rec.LogFile = path
+	h.restore = SetRunner(&recordingRunner{Runner: runner, w: f})
+	return h
+}
+
+// runID returns the history ID of a run without a drive job, e.g. "20240131-201500-dvd-4242".
+// The process ID keeps runs started in the same second (e.g. several rips of ISO images) apart.
+func runID(start time.Time, kind string) string {
+	return fmt.Sprintf("%s-%s-%d", start.Format("20060102-150405"), kind, os.Getpid())
+}
+
+// SetDisc records the disc label.
+func (h *runHistory) SetDisc(disc *DiscInfo) {
+	if h == nil {
+		return
+	}
+	h.rec.DiscLabel = discLabel(disc)
+}
+
+// AddTitle records a title selected for ripping and why it was selected (may be empty).
+func (h *runHistory) AddTitle(t *TitleInfo, reason string) {
+	if h == nil || t == nil {
+		return
+	}
+	h.rec.Titles = append(h.rec.Titles, HistoryTitle{ID: t.ID, Duration: t.Duration.String(), Size: t.Size, Reason: reason})
+}
+
+// SetMatch records the metadata query and the chosen match (nil if nothing matched).
+func (h *runHistory) SetMatch(query string, match *MetadataResult) {
+	if h == nil {
+		return
+	}
+	h.rec.Query = query
+	h.rec.Match = match
+}
+
+// SetOutput records the output directory and the final media files.
+func (h *runHistory) SetOutput(dir string, files []string) {
+	if h == nil {
+		return
+	}
+	h.rec.OutputDir = dir
+	h.rec.Files = files
+}
+
+// Finish saves the record with the run's result. err is nil for a successful run.
+func (h *runHistory) Finish(err error) {
+	if h == nil {
+		return
+	}
+	if h.restore != nil {
+		h.restore()
+		h.restore = nil
+	}
+	if h.logFile != nil {
+		h.logFile.Close()
+		h.logFile = nil
+	}
+
+	h.rec.FinishedAt = time.Now()
+	h.rec.Status = "success"
+	if err != nil {
+		h.rec.Status = "failed"
+		h.rec.Error = err.Error()
+	}
+	if err := appendHistory(h.rec); err != nil {
+		fmt.Printf("Warning: Could not save the run in the history: %v\n", err)
+		return
+	}
+	fmt.Printf("Saved as %s (see rip show %s)\n", h.rec.ID, h.rec.ID)
+}
+
+// historyDir returns the directory holding the history file and run logs, creating it if needed.
+func historyDir() (string, error) {
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "history")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating history directory %s: %v", dir, err)
+	}
+	return dir, nil
+}
+
+// appendHistory appends a record to the history file.
+// The file is locked while writing so rips finishing at the same time do not mix their lines.
+func appendHistory(rec HistoryRecord) error {
+	dir, err := historyDir()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("error encoding history record: %v", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, historyFileName), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening history file: %v", err)
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("error locking history file: %v", err)
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing history file: %v", err)
+	}
+	return nil
+}
+
+// loadHistory reads all history records, oldest first. Lines that cannot be parsed are skipped.
+func loadHistory() ([]HistoryRecord, error) {
+	dir, err := historyDir()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(dir, historyFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening history file: %v", err)
+	}
+	defer f.Close()
+
+	var records []HistoryRecord
+	scanLines(f, func(line string) {
+		var rec HistoryRecord
+		if json.Unmarshal([]byte(line), &rec) == nil {
+			records = append(records, rec)
+		}
+	})
+	return records, nil
+}
+
+// recordingRunner is a Runner that writes every command and its output to a log before passing
+// the output on. MakeMKV's PRGV progress lines are left out; they only repeat the percentages.
+type recordingRunner struct {
+	Runner
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Run runs the command through the wrapped runner and logs its output.
+func (r *recordingRunner) Run(name string, args ...string) (string, error) {
+	out, err := r.Runner.Run(name, args...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.w, "$ %s\n", commandLine(name, args...))
+	io.WriteString(r.w, out)
+	if out != "" && !strings.HasSuffix(out, "\n") {
+		io.WriteString(r.w, "\n")
+	}
+	if err != nil {
+		fmt.Fprintf(r.w, "! %v\n", err)
+	}
+	return out, err
+}
+
+// Stream runs the command through the wrapped runner and logs each line as it arrives.
+func (r *recordingRunner) Stream(name string, args []string, onLine func(line string)) error {
+	r.mu.Lock()
+	fmt.Fprintf(r.w, "$ %s\n", commandLine(name, args...))
+	r.mu.Unlock()
+
+	err := r.Runner.Stream(name, args, func(line string) {
+		if !strings.HasPrefix(line, "PRGV:") {
+			r.mu.Lock()
+			fmt.Fprintln(r.w, line)
+			r.mu.Unlock()
+		}
+		onLine(line)
+	})
+	if err != nil {
+		r.mu.Lock()
+		fmt.Fprintf(r.w, "! %v\n", err)
+		r.mu.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/briandowns/spinner"
+)
+
+// Runner runs external tools such as makemkvcon, filebot and ffprobe.
+// Every external command in the cmd package goes through the package-level runner,
+// so the workflows can be exercised with a FakeRunner (see runner_fake_test.go) instead of real drives and tools.
+type Runner interface {
+	// Run runs the command and returns its standard output.
+	// If the command fails, the returned error includes what it wrote to stderr.
+	Run(name string, args ...string) (string, error)
+
+	// Stream runs the command and calls onLine for every line it writes to stdout or stderr.
+	// onLine is never called concurrently.
+	Stream(name string, args []string, onLine func(line string)) error
+
+	// LookPath reports where the named tool is installed, like exec.LookPath.
+	LookPath(name string) (string, error)
+}
+
+// runner is the Runner used for all external commands.
+var runner Runner = execRunner{}
+
+// contextRunner is implemented by runners that can stop their commands when a context is cancelled.
+type contextRunner interface {
+	// WithContext returns a runner whose commands are stopped when ctx is cancelled.
+	WithContext(ctx context.Context) Runner
+}
+
+// runnerWithContext returns r bound to ctx if r supports cancellation, and r itself otherwise.
+// The rip workflows install it with SetRunner so Ctrl-C stops a running makemkvcon.
+func runnerWithContext(r Runner, ctx context.Context) Runner {
+	if cr, ok := r.(contextRunner); ok {
+		return cr.WithContext(ctx)
+	}
+	return r
+}
+
+// dirRunner is implemented by runners that can run commands in another working directory.
+type dirRunner interface {
+	// InDir returns a runner whose commands run in dir.
+	InDir(dir string) Runner
+}
+
+// runnerInDir returns r running its commands in dir, so tools that must be started in a
+// directory (./configure, make) are run without a shell and "cd". Runners that do not
+// support it are returned as they are.
+func runnerInDir(r Runner, dir string) Runner {
+	if dr, ok := r.(dirRunner); ok {
+		return dr.InDir(dir)
+	}
+	return r
+}
+
+// SetRunner replaces the package-level runner and returns a function that restores the previous one.
+func SetRunner(r Runner) (restore func()) {
+	previous := runner
+	runner = r
+	return func() { runner = previous }
+}
+
+// commandStopDelay is how long a cancelled command gets to exit after being interrupted
+// before it is killed. makemkvcon uses it to close the file it is writing.
+const commandStopDelay = 10 * time.Second
+
+// execRunner runs commands on the local machine with os/exec.
+// Arguments are passed to the command as they are, never through a shell, so titles and
+// disc labels with quotes, $() or backticks cannot run anything.
+type execRunner struct {
+	ctx context.Context // Stops running commands when cancelled; nil means never
+	dir string          // Working directory of the commands; empty means the current one
+}
+
+// WithContext returns an execRunner whose commands are interrupted when ctx is cancelled.
+func (r execRunner) WithContext(ctx context.Context) Runner {
+	return execRunner{ctx: ctx, dir: r.dir}
+}
+
+// InDir returns an execRunner whose commands run in dir.
+func (r execRunner) InDir(dir string) Runner {
+	return execRunner{ctx: r.ctx, dir: dir}
+}
+
+// command creates the command. If the runner has a context, cancelling it sends the command
+// an interrupt and kills it if it has not exited after commandStopDelay.
+func (r execRunner) command(name string, args ...string) *exec.Cmd {
+	if r.ctx == nil {
+		c := exec.Command(name, args...)
+		c.Dir = r.dir
+		return c
+	}
+	c := exec.CommandContext(r.ctx, name, args...)
+	c.Cancel = func() error { return c.Process.Signal(os.Interrupt) }
+	c.WaitDelay = commandStopDelay
+	c.Dir = r.dir
+	return c
+}
+
+// Run runs the command and returns its standard output.
+func (r execRunner) Run(name string, args ...string) (string, error) {
+	out, err := r.command(name, args...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		err = fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return string(out), err
+}
+
+// Stream runs the command and passes each output line to onLine as it is written.
+func (r execRunner) Stream(name string, args []string, onLine func(line string)) error {
+	c := r.command(name, args...)
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("error creating stdout pipe for %s: %v", name, err)
+	}
+	stderr, err := c.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("error creating stderr pipe for %s: %v", name, err)
+	}
+	if err := c.Start(); err != nil {
+		return err
+	}
+
+	// Read both pipes concurrently, but hand lines to onLine one at a time
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, r := range []io.Reader{stdout, stderr} {
+		wg.Add(1)
+		go func(r io.Reader) {
+			defer wg.Done()
+			scanLines(r, func(line string) {
+				mu.Lock()
+				defer mu.Unlock()
+				onLine(line)
+			})
+		}(r)
+	}
+	wg.Wait()
+
+	return c.Wait()
+}
+
+// LookPath searches for the tool in PATH.
+func (execRunner) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
+
+// scanLines calls onLine for every line read from r.
+// Lines may be up to 1 MB long, which is enough for makemkvcon robot output.
+func scanLines(r io.Reader, onLine func(line string)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		onLine(scanner.Text())
+	}
+}
+
+// runWithSpinner runs a command through the runner while showing a spinner on stderr.
+//
+// Parameters:
+//
+//	msg - the message shown next to the spinner (e.g. "Reading disc...")
+//	name - the command to run
+//	args - the command arguments
+//
+// Returns the command output and any error from the command.
+func runWithSpinner(msg, name string, args ...string) (string, error) {
+	s := spinner.New(spinner.CharSets[1], 100*time.Millisecond)
+	s.Suffix = " " + msg
+	s.Writer = os.Stderr
+	s.Start()
+	defer s.Stop()
+
+	return runner.Run(name, args...)
+}
+
+// commandLine formats a command for display in log messages.
+// Arguments with spaces or characters a shell treats specially are single quoted, so a line
+// copied from the log into a shell runs the same command and nothing else.
+func commandLine(name string, args ...string) string {
+	parts := []string{shellQuote(name)}
+	for _, arg := range args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellSpecial holds the characters that make a shell read an argument as more than plain text.
+const shellSpecial = " \t\n'\"`$\\;&|<>()*?[]{}~#!"
+
+// shellQuote single quotes arg if a shell would not read it as it is. A single quote in arg
+// closes the quotes, is written escaped, and opens them again:
+//
+//	Ocean's Eleven -> 'Ocean'\''s Eleven'
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, shellSpecial) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
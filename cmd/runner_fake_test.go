@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// errCommandNotFaked is returned by FakeRunner for commands that have no scripted response.
+var errCommandNotFaked = errors.New("fake runner: no response scripted for command")
+
+// FakeRunner is a scripted Runner for exercising the rip workflows without drives or tools.
+// Responses are registered with On and matched against each command in registration order:
+//
+//	fake := NewFakeRunner()
+//	fake.On("makemkvcon", "-r", "info").Return(robotOutput, nil)
+//	fake.On("makemkvcon", "-r", "--progress=-same", "mkv").Do(func(args []string) error {
+//		return os.WriteFile(filepath.Join(args[5], "title_t00.mkv"), nil, 0644)
+//	})
+//	defer SetRunner(fake)()
+//
+// Every command is recorded in Calls, whether or not it matched a response.
+type FakeRunner struct {
+	mu        sync.Mutex
+	responses []*FakeResponse
+
+	// Calls holds every command run through the fake, as name followed by arguments.
+	Calls [][]string
+
+	// Missing lists tools that LookPath should report as not installed.
+	Missing map[string]bool
+}
+
+// FakeResponse is the scripted result for commands matching a prefix.
+type FakeResponse struct {
+	prefix []string
+	output string
+	err    error
+	do     func(args []string) error
+	times  int
+	used   int
+}
+
+// NewFakeRunner returns a FakeRunner with no scripted responses.
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{Missing: map[string]bool{}}
+}
+
+// On registers a response for commands whose name and leading arguments match.
+// An argument of "*" matches any single argument.
+func (f *FakeRunner) On(name string, args ...string) *FakeResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := &FakeResponse{prefix: append([]string{name}, args...)}
+	f.responses = append(f.responses, r)
+	return r
+}
+
+// Return sets the output and error returned for matching commands.
+func (r *FakeResponse) Return(output string, err error) *FakeResponse {
+	r.output = output
+	r.err = err
+	return r
+}
+
+// Do sets a function that runs for matching commands, for side effects such as creating ripped files.
+// The function receives the full argument list (without the command name); its error is returned
+// when the response itself has no error.
+func (r *FakeResponse) Do(fn func(args []string) error) *FakeResponse {
+	r.do = fn
+	return r
+}
+
+// Times limits the response to the first n matching commands; later commands fall through
+// to the next matching response. Zero (the default) means unlimited.
+func (r *FakeResponse) Times(n int) *FakeResponse {
+	r.times = n
+	return r
+}
+
+// WithContext returns a view of the fake that fails every command with the context's error once
+// ctx is cancelled, the way a real command is stopped. It shares the responses and Calls of the fake.
+func (f *FakeRunner) WithContext(ctx context.Context) Runner {
+	return fakeContextRunner{FakeRunner: f, ctx: ctx}
+}
+
+// InDir returns the fake itself: commands are matched and recorded the same way whatever
+// directory they run in.
+func (f *FakeRunner) InDir(string) Runner {
+	return f
+}
+
+// fakeContextRunner is a FakeRunner bound to a context by WithContext.
+type fakeContextRunner struct {
+	*FakeRunner
+	ctx context.Context
+}
+
+// Run fails with the context's error once it is cancelled, and runs the fake otherwise.
+func (r fakeContextRunner) Run(name string, args ...string) (string, error) {
+	if err := r.cancelled(name, args); err != nil {
+		return "", err
+	}
+	return r.FakeRunner.Run(name, args...)
+}
+
+// Stream fails with the context's error once it is cancelled, and runs the fake otherwise.
+func (r fakeContextRunner) Stream(name string, args []string, onLine func(line string)) error {
+	if err := r.cancelled(name, args); err != nil {
+		return err
+	}
+	return r.FakeRunner.Stream(name, args, onLine)
+}
+
+// cancelled records the command and returns the context's error if the context is cancelled.
+func (r fakeContextRunner) cancelled(name string, args []string) error {
+	err := r.ctx.Err()
+	if err != nil {
+		r.mu.Lock()
+		r.Calls = append(r.Calls, append([]string{name}, args...))
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// Run records the command and returns the scripted output.
+func (f *FakeRunner) Run(name string, args ...string) (string, error) {
+	r, err := f.respond(name, args)
+	if r == nil {
+		return "", err
+	}
+	return r.output, err
+}
+
+// Stream records the command and passes the scripted output to onLine line by line.
+func (f *FakeRunner) Stream(name string, args []string, onLine func(line string)) error {
+	r, err := f.respond(name, args)
+	if r == nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimRight(r.output, "\n"), "\n") {
+		if line != "" {
+			onLine(line)
+		}
+	}
+	return err
+}
+
+// LookPath reports every tool as installed in /usr/bin unless it is listed in Missing.
+func (f *FakeRunner) LookPath(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Missing[name] {
+		return "", fmt.Errorf("exec: %q: executable file not found in $PATH", name)
+	}
+	return "/usr/bin/" + name, nil
+}
+
+// Called reports how many recorded commands start with the given name and arguments.
+func (f *FakeRunner) Called(name string, args ...string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	prefix := append([]string{name}, args...)
+	count := 0
+	for _, call := range f.Calls {
+		if matchesPrefix(call, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+// respond records the command and runs the first matching response.
+func (f *FakeRunner) respond(name string, args []string) (*FakeResponse, error) {
+	call := append([]string{name}, args...)
+
+	f.mu.Lock()
+	f.Calls = append(f.Calls, call)
+	var match *FakeResponse
+	for _, r := range f.responses {
+		if (r.times == 0 || r.used < r.times) && matchesPrefix(call, r.prefix) {
+			r.used++
+			match = r
+			break
+		}
+	}
+	f.mu.Unlock()
+
+	if match == nil {
+		return nil, fmt.Errorf("%w: %s", errCommandNotFaked, commandLine(name, args...))
+	}
+	if match.do != nil {
+		if err := match.do(args); err != nil && match.err == nil {
+			return match, err
+		}
+	}
+	return match, match.err
+}
+
+// matchesPrefix reports whether call starts with prefix, treating "*" in prefix as a wildcard.
+func matchesPrefix(call, prefix []string) bool {
+	if len(call) < len(prefix) {
+		return false
+	}
+	for i, p := range prefix {
+		if p != "*" && p != call[i] {
+			return false
+		}
+	}
+	return true
+}
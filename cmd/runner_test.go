@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// hostileTitle is a movie name a shell would run commands from if it were pasted into a command line.
+const hostileTitle = "Ocean's `id` $(rm -rf ~)"
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"makemkvcon", "makemkvcon"},
+		{"--minlength=3600", "--minlength=3600"},
+		{"disc:0", "disc:0"},
+		{"/media/Movies/Heat (1995)/Heat (1995).mkv", "'/media/Movies/Heat (1995)/Heat (1995).mkv'"},
+		{"", "''"},
+		{"two words", "'two words'"},
+		{"Ocean's Eleven", `'Ocean'\''s Eleven'`},
+		{"'", `''\'''`},
+		{"$HOME", "'$HOME'"},
+		{"`id`", "'`id`'"},
+		{"$(rm -rf ~)", "'$(rm -rf ~)'"},
+		{"a;b", "'a;b'"},
+		{"a&&b|c", "'a&&b|c'"},
+		{"*.mkv", "'*.mkv'"},
+		{"~", "'~'"},
+		{"#comment", "'#comment'"},
+		{"line\nbreak", "'line\nbreak'"},
+		{`back\slash`, `'back\slash'`},
+		{`say "hi"`, `'say "hi"'`},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.arg); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestCommandLineRunsInShellAsLogged(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh to run the logged command with")
+	}
+	args := []string{hostileTitle, "", "two  spaces", "'", `a\b`, "*", "line\nbreak"}
+	line := commandLine("printf", append([]string{`%s\0`}, args...)...)
+	out, err := exec.Command(sh, "-c", line).Output()
+	if err != nil {
+		t.Fatalf("sh -c %s: %v", line, err)
+	}
+	got := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if !slices.Equal(got, args) {
+		t.Errorf("sh -c %s printed %q, want %q", line, got, args)
+	}
+}
+
+func TestDVDRipPassesTitleAsOneArgument(t *testing.T) {
+	env := newRipEnv(t)
+	env.Fake.Missing["ffprobe"] = true
+	image := filepath.Join(t.TempDir(), hostileTitle+".iso")
+	if err := os.WriteFile(image, []byte("iso"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env.Fake.On("makemkvcon", "-r", "info").Return(robotInfo("1:56:39"), nil)
+	env.Fake.On("makemkvcon", "-r", "--progress=-same", "mkv").Do(ripTitle())
+
+	if err := env.run(t, "dvd", "--source", "iso:"+image, "-c", "Drama", "-m", hostileTitle, "--progress", "none"); err != nil {
+		t.Fatalf("rip dvd: %v", err)
+	}
+
+	if call := findCall(env.Fake, "makemkvcon", "-r", "info"); !slices.Equal(call, []string{"makemkvcon", "-r", "info", "iso:" + image, "--minlength=3600"}) {
+		t.Errorf("info command = %q, want the image as one argument", call)
+	}
+	if call := findCall(env.Fake, "makemkvcon", "-r", "--progress=-same", "mkv"); len(call) < 5 || call[4] != "iso:"+image {
+		t.Errorf("mkv command = %q, want the image as one argument", call)
+	}
+	call := findCall(env.Fake, "filebot", "-list")
+	if i := slices.Index(call, "--q"); i < 0 || i+1 >= len(call) || call[i+1] != hostileTitle {
+		t.Errorf("filebot command = %q, want --q followed by the title as one argument", call)
+	}
+	// Nothing was run in a shell: every command is one of the tools, never sh -c
+	for _, call := range env.Fake.Calls {
+		if !slices.Contains([]string{"makemkvcon", "filebot"}, call[0]) {
+			t.Errorf("unexpected command %q", call)
+		}
+	}
+
+	want := []string{"Drama/" + hostileTitle + "/" + hostileTitle + ".mkv"}
+	if got := treeFiles(t, env.Storage); !slices.Equal(got, want) {
+		t.Errorf("library = %q, want %q", got, want)
+	}
+}
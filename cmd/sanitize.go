@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Unicode normalization forms selectable with paths.unicode.
+const (
+	unicodeNFC  = "nfc"  // Composed characters, as Linux, Windows and Plex expect
+	unicodeNFD  = "nfd"  // Decomposed characters, as macOS HFS+ stores names
+	unicodeNone = "none" // Names are kept as the metadata gives them
+)
+
+// fileNameReserve is the room kept below paths.max_length in file names for what rip adds
+// to them: the extension and temporary suffix while transcoding (".transcoding.mkv"), and
+// a number for a second file of the same name (see renameMKVFile).
+const fileNameReserve = len(".transcoding.mkv") + 3
+
+// minPathsMaxLength is the shortest paths.max_length allowed, so names still say something
+// once fileNameReserve is taken off.
+const minPathsMaxLength = 2 * fileNameReserve
+
+// defaultPathsReplace replaces the characters Windows, SMB and NTFS do not allow in names.
+// "Title: Subtitle" becomes "Title - Subtitle".
+var defaultPathsReplace = [][]string{
+	{": ", " - "},
+	{":", "-"},
+	{"/", "-"},
+	{`\`, "-"},
+	{"?", ""},
+	{"*", ""},
+	{`"`, "'"},
+	{"<", ""},
+	{">", ""},
+	{"|", "-"},
+}
+
+// cleanText applies the configured normalization and replacements to text that becomes part of a
+// name, then removes what can never be in one: path separators, NUL and other control characters.
+// Returns the cleaned text, which may be empty.
+func (p PathsConfig) cleanText(text string) string {
+	switch p.Unicode {
+	case unicodeNFC:
+		text = norm.NFC.String(text)
+	case unicodeNFD:
+		text = norm.NFD.String(text)
+	}
+	for _, pair := range p.Replace {
+		if len(pair) == 2 && pair[0] != "" {
+			text = strings.ReplaceAll(text, pair[0], pair[1])
+		}
+	}
+	text = strings.ReplaceAll(text, "/", "-")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// cleanName makes one folder or file name safe to create under the storage path.
+// On top of cleanText, surrounding spaces and leading and trailing dots are removed (hidden
+// names and names SMB and NTFS cannot hold), "." and ".." become "_", and the name is cut to
+// paths.max_length bytes.
+//
+// Parameters:
+//
+//	name - the folder or file name, without any path separators meant as such
+//	reserve - bytes to keep free below paths.max_length, for an extension added later
+//
+// Returns the safe name; never empty.
+func (p PathsConfig) cleanName(name string, reserve int) string {
+	name = trimName(p.cleanText(name))
+	if limit := p.MaxLength - reserve; p.MaxLength > 0 && len(name) > limit {
+		// Cut at the start of a character, so no character is split
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = trimName(name[:cut])
+	}
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// trimName removes surrounding spaces and leading and trailing dots from a name.
+func trimName(name string) string {
+	return strings.Trim(strings.TrimSpace(name), ". ")
+}
+
+// cleanPath cleans every folder of a relative path made by a naming template (see cleanName).
+// Empty folders, e.g. from an empty field between two slashes, are left out.
+//
+// Parameters:
+//
+//	path - the path, with / between folders
+//	reserve - bytes to keep free in the last name, for an extension added later
+//
+// Returns the safe relative path.
+func (p PathsConfig) cleanPath(path string, reserve int) string {
+	var parts []string
+	for _, part := range strings.Split(path, "/") {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "_"
+	}
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			parts[i] = p.cleanName(part, reserve)
+		} else {
+			parts[i] = p.cleanName(part, 0)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// cleanData cleans the text fields of naming data before a template is filled in with them,
+// so a slash in a title ("AC/DC") cannot add a folder. Empty fields stay empty.
+func (p PathsConfig) cleanData(data NamingData) NamingData {
+	for _, field := range []*string{&data.Title, &data.IMDBID, &data.Genre, &data.EpisodeTitle, &data.Edition, &data.Resolution} {
+		*field = strings.TrimSpace(p.cleanText(*field))
+	}
+	return data
+}
+
+// safeJoin joins relative path elements onto root, making sure the result stays inside root.
+// It is the last check on every library path rip creates; the elements should already have
+// been cleaned (see cleanName and cleanPath).
+//
+// Returns the joined path, or an error if an element is absolute or escapes root with "..".
+func safeJoin(root string, elem ...string) (string, error) {
+	for _, e := range elem {
+		if filepath.IsAbs(e) {
+			return "", fmt.Errorf("refusing to use %q: absolute paths are not allowed in library names", e)
+		}
+	}
+	path := filepath.Join(append([]string{root}, elem...)...)
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to use %q: it is outside %s", path, root)
+	}
+	return path, nil
+}
+
+// validate checks the paths section. Returns one message per problem.
+func (p PathsConfig) validate() []string {
+	var problems []string
+	switch p.Unicode {
+	case unicodeNFC, unicodeNFD, unicodeNone:
+	default:
+		problems = append(problems, fmt.Sprintf("paths.unicode must be %s, %s or %s, got %q", unicodeNFC, unicodeNFD, unicodeNone, p.Unicode))
+	}
+	if p.MaxLength < minPathsMaxLength || p.MaxLength > 255 {
+		problems = append(problems, fmt.Sprintf("paths.max_length must be from %d to 255, got %d", minPathsMaxLength, p.MaxLength))
+	}
+	for i, pair := range p.Replace {
+		if len(pair) != 2 || pair[0] == "" {
+			problems = append(problems, fmt.Sprintf("paths.replace entry %d must be [text, replacement] with non-empty text", i+1))
+		}
+	}
+	return problems
+}
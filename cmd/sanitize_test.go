@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCleanName(t *testing.T) {
+	const nfc, nfd = "Am\u00e9lie", "Ame\u0301lie" // é as one character, and as e and a combining accent
+	tests := []struct {
+		name    string
+		unicode string
+		in      string
+		want    string
+	}{
+		{"dot dot", unicodeNFC, "..", "_"},
+		{"dot", unicodeNFC, ".", "_"},
+		{"empty", unicodeNFC, "", "_"},
+		{"only reserved characters", unicodeNFC, "?*", "_"},
+		{"slash", unicodeNFC, "AC/DC", "AC-DC"},
+		{"backslash", unicodeNFC, `AC\DC`, "AC-DC"},
+		{"colon and question mark", unicodeNFC, "Title: Part?", "Title - Part"},
+		{"colon without a space", unicodeNFC, "10:30", "10-30"},
+		{"other reserved characters", unicodeNFC, `a<b>c|d"e*`, "abc-d'e"},
+		{"trailing dots and spaces", unicodeNFC, "Jr. . ", "Jr"},
+		{"leading dots", unicodeNFC, "...hidden", "hidden"},
+		{"control characters", unicodeNFC, "Tab\there\x00\n", "Tabhere"},
+		{"NFD to NFC", unicodeNFC, nfd, nfc},
+		{"NFC kept as NFC", unicodeNFC, nfc, nfc},
+		{"NFC to NFD", unicodeNFD, nfc, nfd},
+		{"no normalization", unicodeNone, nfd, nfd},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := defaultConfig().Paths
+			p.Unicode = tt.unicode
+			if got := p.cleanName(tt.in, 0); got != tt.want {
+				t.Errorf("cleanName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanNameMaxLength(t *testing.T) {
+	p := defaultConfig().Paths
+	p.MaxLength = minPathsMaxLength
+	a := strings.Repeat("a", p.MaxLength-1)
+	tests := []struct {
+		name    string
+		in      string
+		reserve int
+		want    string
+	}{
+		{"fits", a + "b", 0, a + "b"},
+		{"cut inside a multi-byte character", a + "éb", 0, a},
+		{"cut inside a four-byte character", a[:p.MaxLength-3] + "🎬x", 0, a[:p.MaxLength-3]},
+		{"cut before the reserve", a + "b", fileNameReserve, a[:p.MaxLength-fileNameReserve]},
+		{"spaces and dots at the cut are trimmed", a[:p.MaxLength-3] + " . rest", 0, a[:p.MaxLength-3]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.cleanName(tt.in, tt.reserve)
+			if got != tt.want {
+				t.Errorf("cleanName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if !utf8.ValidString(got) || len(got) > p.MaxLength-tt.reserve {
+				t.Errorf("cleanName(%q) = %q (%d bytes): not valid UTF-8 within %d bytes", tt.in, got, len(got), p.MaxLength-tt.reserve)
+			}
+		})
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	p := defaultConfig().Paths
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Comedy/The Office (2005)", "Comedy/The Office (2005)"},
+		{"Comedy//The Office", "Comedy/The Office"},
+		{"../../etc/passwd", "_/_/etc/passwd"},
+		{"/Drama/Heat/", "Drama/Heat"},
+		{"Sci-Fi/Star Wars: Episode IV?", "Sci-Fi/Star Wars - Episode IV"},
+		{" / ", "_"},
+	}
+	for _, tt := range tests {
+		if got := p.cleanPath(tt.in, 0); got != tt.want {
+			t.Errorf("cleanPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	// A slash in the data never adds a folder
+	data := p.cleanData(NamingData{Title: "AC/DC: Live ", Genre: "Rock/Pop"})
+	if data.Title != "AC-DC - Live" || data.Genre != "Rock-Pop" {
+		t.Errorf("cleanData = %q, %q, want %q, %q", data.Title, data.Genre, "AC-DC - Live", "Rock-Pop")
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	const root = "/plex/storage"
+	tests := []struct {
+		name string
+		elem []string
+		want string // The path, or part of the error
+	}{
+		{"inside", []string{"Drama", "Heat (1995)", "Heat (1995).mkv"}, "/plex/storage/Drama/Heat (1995)/Heat (1995).mkv"},
+		{"name starting with dots", []string{"..hidden"}, "/plex/storage/..hidden"},
+		{"dot dot inside", []string{"Drama/../Comedy"}, "/plex/storage/Comedy"},
+		{"absolute element", []string{"Drama", "/etc/cron.d"}, `refusing to use "/etc/cron.d": absolute paths are not allowed`},
+		{"dot dot element", []string{"..", "other"}, "refusing to use \"/plex/other\": it is outside /plex/storage"},
+		{"dot dot prefix", []string{"../storage2/Heat"}, "it is outside /plex/storage"},
+		{"escaping through a folder", []string{"Drama", "../../../etc"}, "it is outside /plex/storage"},
+		{"root's parent", []string{"Drama", "..", ".."}, "it is outside /plex/storage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := safeJoin(root, tt.elem...)
+			if err != nil {
+				got = err.Error()
+			}
+			if !strings.Contains(got, tt.want) || (err == nil && got != tt.want) {
+				t.Errorf("safeJoin(%q) = %q, want %q", tt.elem, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Rips coordinate through lock files in the config directory, so the limits hold across every rip
+// process on the machine, whether it was started from the command line, by rip watch or by rip web:
+//
+//   - locks/drive-<name>.lock is held by the job ripping from that drive, so two jobs never share a drive.
+//     The file holds the job description shown by rip jobs.
+//   - locks/<kind>-<n>.lock are the slots limiting concurrent metadata lookups (FileBot or TMDB)
+//     and disk writes (MakeMKV rips) to limits.metadata_jobs and limits.write_jobs.
+//
+// The locks are flock(2) locks, which the kernel releases when the process exits, so a crashed
+// rip never leaves a drive locked.
+
+// Slot kinds for acquireSlot.
+const (
+	slotMetadata = "metadata"
+	slotWrite    = "write"
+)
+
+// slotPollInterval is how often a job waiting for a slot retries.
+const slotPollInterval = 2 * time.Second
+
+// JobInfo describes a running rip job. It is stored in the job's drive lock file.
+type JobInfo struct {
+	ID        string    `json:"id"`
+	PID       int       `json:"pid"`
+	Device    string    `json:"device"`
+	Kind      string    `json:"kind"`  // "dvd", "tv", or "watch" while rip watch reads a disc
+	Title     string    `json:"title"` // What is being ripped (e.g. the query or show and season-disc)
+	Phase     string    `json:"phase"` // Current step (e.g. "reading disc", "ripping")
+	StartedAt time.Time `json:"startedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Job is a rip job holding the lock on its drive.
+type Job struct {
+	Info JobInfo
+	file *os.File
+}
+
+// lockDir returns the directory holding the drive and slot lock files.
+func lockDir() (string, error) {
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "locks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating lock directory %s: %v", dir, err)
+	}
+	return dir, nil
+}
+
+// deviceLockName turns a device path into a lock file name, e.g. "/dev/sr0" -> "drive-sr0.lock".
+func deviceLockName(device string) string {
+	name := strings.TrimPrefix(device, "/dev/")
+	name = strings.NewReplacer("/", "_", ":", "_").Replace(name)
+	return "drive-" + name + ".lock"
+}
+
+// newJobID returns an ID for a job on a device, e.g. "20240131-201500-sr0".
+func newJobID(device string) string {
+	name := strings.TrimSuffix(strings.TrimPrefix(deviceLockName(device), "drive-"), ".lock")
+	return time.Now().Format("20060102-150405") + "-" + name
+}
+
+// startJob locks the drive for a new job.
+// It fails immediately, naming the job holding the drive, if another job is using the drive.
+//
+// Parameters:
+//
+//	device - the device path of the drive (e.g. "/dev/sr0")
+//	kind - the job kind, "dvd", "tv" or "watch"
+//	title - a short description of what is being ripped
+//
+// Returns the job, which must be finished with Finish.
+func startJob(device, kind, title string) (*Job, error) {
+	dir, err := lockDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, deviceLockName(device))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening drive lock %s: %v", path, err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if holder, readErr := readJobInfo(path); readErr == nil {
+			return nil, fmt.Errorf("drive %s is in use by job %s (%s %s, pid %d)", device, holder.ID, holder.Kind, holder.Title, holder.PID)
+		}
+		return nil, fmt.Errorf("drive %s is in use by another rip", device)
+	}
+
+	now := time.Now()
+	job := &Job{
+		Info: JobInfo{
+			ID:        newJobID(device),
+			PID:       os.Getpid(),
+			Device:    device,
+			Kind:      kind,
+			Title:     title,
+			Phase:     "starting",
+			StartedAt: now,
+			UpdatedAt: now,
+		},
+		file: f,
+	}
+	job.write()
+	return job, nil
+}
+
+// SetPhase records the job's current step for rip jobs. It is safe to call on a nil job.
+func (j *Job) SetPhase(phase string) {
+	if j == nil {
+		return
+	}
+	j.Info.Phase = phase
+	j.Info.UpdatedAt = time.Now()
+	j.write()
+}
+
+// Finish releases the drive lock. It is safe to call on a nil job and more than once.
+func (j *Job) Finish() {
+	if j == nil || j.file == nil {
+		return
+	}
+	j.file.Truncate(0)
+	syscall.Flock(int(j.file.Fd()), syscall.LOCK_UN)
+	j.file.Close()
+	j.file = nil
+}
+
+// write stores the job description in the lock file.
+func (j *Job) write() {
+	data, err := json.Marshal(j.Info)
+	if err != nil {
+		return
+	}
+	j.file.Truncate(0)
+	j.file.WriteAt(data, 0)
+}
+
+// readJobInfo reads the job description from a drive lock file.
+func readJobInfo(path string) (*JobInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var info JobInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// isLocked reports whether another open file holds a lock on path.
+func isLocked(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err != nil {
+		return true
+	}
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return false
+}
+
+// driveInUse reports whether a job holds the lock on a drive.
+func driveInUse(device string) bool {
+	dir, err := lockDir()
+	if err != nil {
+		return false
+	}
+	return isLocked(filepath.Join(dir, deviceLockName(device)))
+}
+
+// runningJobs returns the jobs currently holding a drive lock, ordered by device.
+func runningJobs() ([]JobInfo, error) {
+	dir, err := lockDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "drive-*.lock"))
+	var jobs []JobInfo
+	for _, path := range paths {
+		if !isLocked(path) {
+			continue
+		}
+		info, err := readJobInfo(path)
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, *info)
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].Device < jobs[k].Device })
+	return jobs, nil
+}
+
+// acquireSlot waits for one of limit slots of the given kind and returns a function that releases it.
+// While waiting, the job phase shows what it is waiting for.
+//
+// Parameters:
+//
+//	ctx - stops the wait when cancelled
+//	job - the job waiting for the slot (may be nil)
+//	kind - slotMetadata or slotWrite
+//	limit - the number of slots; zero or less means unlimited
+//
+// Returns the release function, or an error if the lock files cannot be used or ctx is cancelled.
+func acquireSlot(ctx context.Context, job *Job, kind string, limit int) (release func(), err error) {
+	if limit <= 0 {
+		return func() {}, nil
+	}
+	dir, err := lockDir()
+	if err != nil {
+		return nil, err
+	}
+
+	waiting := false
+	for {
+		for n := 1; n <= limit; n++ {
+			path := filepath.Join(dir, fmt.Sprintf("%s-%d.lock", kind, n))
+			f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+			if err != nil {
+				return nil, fmt.Errorf("error opening slot lock %s: %v", path, err)
+			}
+			if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+				f.Close()
+				if errors.Is(err, syscall.EWOULDBLOCK) {
+					continue
+				}
+				return nil, fmt.Errorf("error locking slot %s: %v", path, err)
+			}
+			return func() {
+				syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+				f.Close()
+			}, nil
+		}
+
+		if !waiting {
+			waiting = true
+			fmt.Printf("Waiting for a free %s slot (limit %d)...\n", kind, limit)
+			job.SetPhase("waiting for " + kind + " slot")
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(slotPollInterval):
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// DiscSource is where a rip reads from: a physical drive, an ISO image or a disc folder
+// such as a VIDEO_TS or BDMV backup.
+type DiscSource struct {
+	Spec   string // MakeMKV source specification (e.g. "disc:0", "dev:/dev/rdisk6", "iso:/backups/movie.iso")
+	Device string // Device path used to eject the disc; empty for images and folders
+	Path   string // Image or folder path; empty for drives
+}
+
+// IsDevice reports whether the source is a physical drive that can be ejected.
+func (s *DiscSource) IsDevice() bool {
+	return s.Device != ""
+}
+
+// String returns a description of the source for log messages.
+func (s *DiscSource) String() string {
+	if s.IsDevice() {
+		return s.Device
+	}
+	return s.Path
+}
+
+// Name returns a fallback title for the source: the image or folder name without extension,
+// skipping VIDEO_TS and BDMV folder names. It is empty for drives.
+func (s *DiscSource) Name() string {
+	if s.Path == "" {
+		return ""
+	}
+	path := s.Path
+	if base := strings.ToUpper(filepath.Base(path)); base == "VIDEO_TS" || base == "BDMV" {
+		path = filepath.Dir(path)
+	}
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// parseSource turns a --source or --device value into a DiscSource.
+// Accepted forms:
+//
+//	iso:/path/file.iso   - an ISO image
+//	file:/path/VIDEO_TS  - a DVD or Blu-ray folder (VIDEO_TS, BDMV or the folder containing them)
+//	disc:0               - a MakeMKV drive index
+//	dev:/dev/rdisk6      - a device path passed to MakeMKV as-is
+//	/dev/sr0             - a device path, converted with formatDriveForMakeMKV
+//
+// A plain path to an .iso file or a directory is treated as iso: or file: respectively.
+//
+// Returns an error if an image or folder does not exist or is not what its prefix says.
+func parseSource(value string) (*DiscSource, error) {
+	prefix, rest, hasPrefix := strings.Cut(value, ":")
+	if !hasPrefix || (prefix != "iso" && prefix != "file" && prefix != "disc" && prefix != "dev") {
+		// No MakeMKV prefix: detect images and folders, otherwise it is a device path
+		info, err := os.Stat(value)
+		switch {
+		case err == nil && info.IsDir():
+			prefix, rest = "file", value
+		case err == nil && strings.EqualFold(filepath.Ext(value), ".iso"):
+			prefix, rest = "iso", value
+		default:
+			return &DiscSource{Spec: formatDriveForMakeMKV(value), Device: value}, nil
+		}
+	}
+
+	switch prefix {
+	case "disc":
+		return &DiscSource{Spec: value, Device: extractDevicePath(value)}, nil
+	case "dev":
+		return &DiscSource{Spec: value, Device: rest}, nil
+	}
+
+	path, err := filepath.Abs(rest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source path %s: %v", rest, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("source %s not found: %v", path, err)
+	}
+	if prefix == "iso" {
+		if info.IsDir() {
+			return nil, fmt.Errorf("source %s is a directory; use file:%s for disc folders", path, path)
+		}
+		return &DiscSource{Spec: "iso:" + path, Path: path}, nil
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("source %s is not a directory; use iso:%s for disc images", path, path)
+	}
+	if !isDiscFolder(path) {
+		return nil, fmt.Errorf("source %s does not look like a disc folder (no VIDEO_TS or BDMV)", path)
+	}
+	return &DiscSource{Spec: "file:" + path, Path: path}, nil
+}
+
+// isDiscFolder reports whether dir is a VIDEO_TS or BDMV folder, or contains one.
+func isDiscFolder(dir string) bool {
+	base := strings.ToUpper(filepath.Base(dir))
+	if base == "VIDEO_TS" || base == "BDMV" {
+		return true
+	}
+	for _, sub := range []string{"VIDEO_TS", "BDMV"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceFromFlags returns the rip source from the --source flag, or from --device if --source is not set,
+// falling back to the device from the config.
+func sourceFromFlags(cmd *cobra.Command) (*DiscSource, error) {
+	if source, _ := cmd.Flags().GetString("source"); source != "" {
+		return parseSource(source)
+	}
+	device, _ := cmd.Flags().GetString("device")
+	if device == "" {
+		device = AppConfig.Device
+	}
+	return parseSource(device)
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// spaceHeadroomPercent is added to the title sizes MakeMKV reports before comparing them with the
+// free space. The sizes are MakeMKV's estimates, and the finished MKV can come out slightly larger.
+const spaceHeadroomPercent = 5
+
+// titlesSize adds up the sizes of the titles that will be ripped.
+//
+// Returns the total in bytes, and false if MakeMKV did not report the size of every title.
+func titlesSize(titles []*TitleInfo) (int64, bool) {
+	var total int64
+	for _, t := range titles {
+		if t == nil || t.SizeBytes <= 0 {
+			return total, false
+		}
+		total += t.SizeBytes
+	}
+	return total, true
+}
+
+// tvRipTitles returns the titles runTVMakeMKV rips: every title of at least tv.min_episode_length,
+// including "Play All" titles, which are only removed after ripping.
+func tvRipTitles(disc *DiscInfo) []*TitleInfo {
+	var titles []*TitleInfo
+	for _, t := range disc.Titles {
+		if t.Duration >= AppConfig.TV.MinEpisodeLength {
+			titles = append(titles, t)
+		}
+	}
+	return titles
+}
+
+// CheckSpace makes sure there is room for a rip before it starts: the staging directory must have
+// room for the ripped titles, and so must the library directory when it is on another filesystem
+// (the files are then copied into the library rather than renamed).
+//
+// Parameters:
+//
+//	titles - the titles that will be ripped, with the sizes read from the disc
+//	libraryDir - the directory the files are finally moved to
+//
+// Returns an error saying how much space is needed and how much is free if a filesystem is too full.
+// Titles of unknown size skip the check with a warning.
+func (s *stagingArea) CheckSpace(titles []*TitleInfo, libraryDir string) error {
+	size, known := titlesSize(titles)
+	if !known {
+		fmt.Println("Warning: MakeMKV did not report the title sizes; skipping the free space check")
+		return nil
+	}
+	need := uint64(size) + uint64(size)*spaceHeadroomPercent/100
+	fmt.Printf("This rip needs about %s (%d title(s))\n", formatBytes(uint64(size)), len(titles))
+
+	// The staging root rather than this rip's temporary directory, which means nothing to the user
+	paths := []string{filepath.Dir(s.Dir)}
+	if target := s.targetDir(libraryDir); !sameFilesystem(s.Dir, target) {
+		paths = append(paths, target)
+	}
+	for _, p := range paths {
+		free, err := freeSpace(p)
+		if err != nil {
+			fmt.Printf("Warning: Could not check free space: %v\n", err)
+			continue
+		}
+		if free < need {
+			return fmt.Errorf("not enough free space on %s: this rip needs about %s (including %d%% headroom) but only %s is free",
+				existingDir(p), formatBytes(need), spaceHeadroomPercent, formatBytes(free))
+		}
+	}
+	return nil
+}
+
+// freeSpace returns the bytes available to unprivileged users on the filesystem holding path.
+// If path does not exist yet, its nearest existing parent is used.
+func freeSpace(path string) (uint64, error) {
+	dir := existingDir(path)
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, fmt.Errorf("error reading free space of %s: %v", dir, err)
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
+
+// sameFilesystem reports whether a and b (or their nearest existing parents) are on the same filesystem.
+func sameFilesystem(a, b string) bool {
+	infoA, errA := os.Stat(existingDir(a))
+	infoB, errB := os.Stat(existingDir(b))
+	if errA != nil || errB != nil {
+		return false
+	}
+	statA, okA := infoA.Sys().(*syscall.Stat_t)
+	statB, okB := infoB.Sys().(*syscall.Stat_t)
+	return okA && okB && statA.Dev == statB.Dev
+}
+
+// existingDir returns path if it exists, otherwise its nearest existing parent.
+func existingDir(path string) string {
+	dir := filepath.Clean(path)
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			return dir
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// formatBytes formats a byte count for messages, e.g. "4.3 GB".
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit*unit*unit {
+		return fmt.Sprintf("%.0f MB", float64(n)/(unit*unit))
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/(unit*unit*unit)), ".0") + " GB"
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTitlesSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		titles []*TitleInfo
+		want   int64
+		known  bool
+	}{
+		{"no titles", nil, 0, true},
+		{"one title", []*TitleInfo{testTitle(0, 118, "", 30)}, 30 << 30, true},
+		{"several titles", []*TitleInfo{testTitle(0, 22, "", 1), testTitle(1, 23, "", 2)}, 3 << 30, true},
+		{"unknown size", []*TitleInfo{testTitle(0, 22, "", 1), testTitle(1, 23, "", 0)}, 1 << 30, false},
+		{"missing title", []*TitleInfo{testTitle(0, 22, "", 1), nil}, 1 << 30, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, known := titlesSize(tt.titles)
+			if known != tt.known || (known && got != tt.want) {
+				t.Errorf("titlesSize = %d, %v, want %d, %v", got, known, tt.want, tt.known)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	const mb, gb = 1 << 20, 1 << 30
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0 MB"},
+		{700 * mb, "700 MB"},
+		{gb - mb, "1023 MB"},
+		{gb, "1 GB"},
+		{gb + gb/2, "1.5 GB"},
+		{4*gb + 300*mb, "4.3 GB"},
+		{2048 * gb, "2048 GB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSpace(t *testing.T) {
+	root := t.TempDir()
+	AppConfig = defaultConfig()
+	AppConfig.StoragePath = root
+	stage := &stagingArea{Dir: filepath.Join(root, stagingDirName, "rip-1")}
+	libraryDir := filepath.Join(root, "Drama", "Heat")
+
+	if err := stage.CheckSpace([]*TitleInfo{testTitle(0, 118, "", 0)}, libraryDir); err != nil {
+		t.Errorf("CheckSpace with an unknown size = %v, want the check skipped", err)
+	}
+	if err := stage.CheckSpace([]*TitleInfo{{SizeBytes: 1 << 20}}, libraryDir); err != nil {
+		t.Errorf("CheckSpace for 1 MB = %v", err)
+	}
+
+	// A petabyte does not fit; the message includes the headroom
+	size := int64(1 << 50)
+	err := stage.CheckSpace([]*TitleInfo{{SizeBytes: size}}, libraryDir)
+	want := "needs about " + formatBytes(uint64(size+size*spaceHeadroomPercent/100)) + " (including 5% headroom)"
+	if err == nil || !strings.Contains(err.Error(), want) || !strings.Contains(err.Error(), "not enough free space on "+root) {
+		t.Errorf("CheckSpace for 1 PB = %v, want %q on %s", err, want, root)
+	}
+}
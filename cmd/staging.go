@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// stagingDirName is the staging directory created at the root of the storage path or MergerFS branch.
+// The leading dot keeps Plex and Jellyfin from scanning it.
+const stagingDirName = ".rip-staging"
+
+// stagingArea is a scratch directory where MakeMKV and FileBot work on a rip before the finished
+// files are moved into the library, so a failed or partial rip never shows up in Plex or Jellyfin.
+//
+// When the storage path is a MergerFS pool, the rip is placed on a branch chosen by placement_policy:
+// the staging directory is created on that branch, and finished files are moved to the same relative
+// path on it. Moving within one filesystem is a rename, so a file appears in the library complete or
+// not at all.
+type stagingArea struct {
+	Dir    string // Directory the rip writes to
+	Branch string // MergerFS branch the finished files are moved to, or empty when not using a pool
+
+	createdDirs []string // Library directories Commit created, outermost first
+	moved       []string // Files Commit moved into the library, at their real (branch) paths
+	keep        bool     // Rollback leaves the staging directory in place (see Keep)
+}
+
+// newStagingArea creates the staging directory for a rip.
+// When the storage path is a MergerFS pool, the branch the rip is placed on is chosen first (see
+// chooseBranch). The staging root is staging_path from the config if set; otherwise that branch,
+// or the storage path itself.
+//
+// Parameters:
+//
+//	id - a name for the rip, used for the staging directory (e.g. the job ID)
+//	libraryDir - the directory the finished files go to, under the storage path
+//
+// Returns the staging area, or an error if the directory cannot be created.
+func newStagingArea(id, libraryDir string) (*stagingArea, error) {
+	area := &stagingArea{}
+	if disks := GetMergerFSDisks(); len(disks) > 0 {
+		branch, err := chooseBranch(disks, libraryDir)
+		if err != nil {
+			return nil, fmt.Errorf("error choosing a MergerFS branch for the rip: %v", err)
+		}
+		area.Branch = branch
+	}
+	root := AppConfig.StagingPath
+	if root == "" {
+		root = filepath.Join(AppConfig.StoragePath, stagingDirName)
+		if area.Branch != "" {
+			root = filepath.Join(area.Branch, stagingDirName)
+		}
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("error creating staging directory %s: %v", root, err)
+	}
+	dir, err := os.MkdirTemp(root, id+"-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating staging directory in %s: %v", root, err)
+	}
+	area.Dir = dir
+	return area, nil
+}
+
+// Files returns the MKV files in the staging directory, sorted by name.
+func (s *stagingArea) Files() []string {
+	return listMKVFiles(s.Dir)
+}
+
+// Verify checks that the staged files are complete before they are moved into the library:
+// every file must be non-empty, and, if ffprobe is installed, readable with a positive duration.
+func (s *stagingArea) Verify(files []string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no MKV files in staging directory %s", s.Dir)
+	}
+	_, ffprobeErr := runner.LookPath("ffprobe")
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return fmt.Errorf("staged file missing: %v", err)
+		}
+		if info.Size() == 0 {
+			return fmt.Errorf("staged file %s is empty", filepath.Base(f))
+		}
+		if ffprobeErr != nil {
+			continue
+		}
+		out, err := runner.Run("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", f)
+		if err != nil {
+			return fmt.Errorf("staged file %s is not readable: %v", filepath.Base(f), err)
+		}
+		if d, err := strconv.ParseFloat(strings.TrimSpace(out), 64); err != nil || d <= 0 {
+			return fmt.Errorf("staged file %s has no duration", filepath.Base(f))
+		}
+	}
+	return nil
+}
+
+// Commit moves the staged files into a library directory and removes the staging directory.
+// Existing library files are never overwritten.
+//
+// Parameters:
+//
+//	files - the staged files to move
+//	libraryDir - the destination directory under the storage path
+//
+// Returns the final paths (under the storage path), or an error; on error the files that were not
+// moved stay in the staging directory.
+func (s *stagingArea) Commit(files []string, libraryDir string) ([]string, error) {
+	targetDir := s.targetDir(libraryDir)
+	s.createdDirs = append(s.createdDirs, missingDirs(targetDir)...)
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating library directory %s: %v", targetDir, err)
+	}
+
+	// Check every target first, so nothing is moved if any name is taken
+	for _, f := range files {
+		name := filepath.Base(f)
+		for _, dir := range []string{targetDir, libraryDir} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return nil, fmt.Errorf("%s already exists in %s", name, libraryDir)
+			}
+		}
+	}
+
+	var moved []string
+	for _, f := range files {
+		name := filepath.Base(f)
+		if err := moveFile(f, filepath.Join(targetDir, name)); err != nil {
+			return moved, err
+		}
+		s.moved = append(s.moved, filepath.Join(targetDir, name))
+		fmt.Printf("Moved into library: %s\n", filepath.Join(libraryDir, name))
+		moved = append(moved, filepath.Join(libraryDir, name))
+	}
+	s.Remove()
+	return moved, nil
+}
+
+// Remove deletes the staging directory and everything left in it.
+func (s *stagingArea) Remove() {
+	if s == nil || s.Dir == "" {
+		return
+	}
+	if err := os.RemoveAll(s.Dir); err != nil {
+		fmt.Printf("Warning: Could not remove staging directory %s: %v\n", s.Dir, err)
+	}
+}
+
+// targetDir returns the directory the staged files of libraryDir are moved to. In a MergerFS pool
+// that is the same relative path on the chosen branch, so the files appear in the pool at libraryDir.
+func (s *stagingArea) targetDir(libraryDir string) string {
+	if s.Branch != "" {
+		if rel, err := filepath.Rel(AppConfig.StoragePath, libraryDir); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.Join(s.Branch, rel)
+		}
+	}
+	return libraryDir
+}
+
+// Keep makes Rollback leave the staging directory and the files in it in place, for rips whose
+// files are complete but cannot be moved into the library as they are.
+func (s *stagingArea) Keep() {
+	s.keep = true
+}
+
+// Rollback undoes the rip: it deletes the files Commit moved into the library, the library
+// directories Commit created (if they are empty again) and the staging directory, unless Keep
+// was called. It is safe to call on a nil staging area.
+func (s *stagingArea) Rollback() {
+	if s == nil {
+		return
+	}
+	for _, f := range s.moved {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Warning: Could not remove %s: %v\n", f, err)
+			continue
+		}
+		fmt.Printf("Removed from library: %s\n", f)
+	}
+	s.moved = nil
+	for i := len(s.createdDirs) - 1; i >= 0; i-- {
+		// Remove fails on directories that are not empty, which are kept on purpose
+		os.Remove(s.createdDirs[i])
+	}
+	s.createdDirs = nil
+	if s.keep {
+		fmt.Printf("The ripped files are kept in %s\n", s.Dir)
+		return
+	}
+	s.Remove()
+}
+
+// missingDirs returns dir and those of its parents that do not exist yet, outermost first.
+func missingDirs(dir string) []string {
+	var missing []string
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil || filepath.Dir(d) == d {
+			break
+		}
+		missing = append([]string{d}, missing...)
+	}
+	return missing
+}
+
+// renameFile renames a file; tests replace it to simulate moves across filesystems.
+var renameFile = os.Rename
+
+// moveFile renames src to dst. If they are on different filesystems, the file is copied to a
+// temporary name next to dst, synced, renamed into place and only then removed from src.
+func moveFile(src, dst string) error {
+	err := renameFile(src, dst)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return fmt.Errorf("error moving %s to %s: %v", filepath.Base(src), dst, err)
+	}
+
+	fmt.Printf("Copying %s into the library (staging is on a different filesystem)...\n", filepath.Base(src))
+	tmp := filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".partial")
+	if err := copyFile(src, tmp); err != nil {
+		return err
+	}
+	if err := renameFile(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error moving %s into place: %v", filepath.Base(dst), err)
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies src to dst and syncs dst to disk. dst must not exist; if the copy fails, the
+// partial dst is removed.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %v", src, err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("error copying %s: %v", filepath.Base(src), err)
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("error syncing %s: %v", dst, err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("error writing %s: %v", dst, err)
+	}
+	return nil
+}
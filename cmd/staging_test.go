@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// writeFiles creates files with their names as content in dir and returns their paths.
+func writeFiles(t *testing.T, dir string, names ...string) []string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// exists reports whether path exists.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// crossDeviceRenames makes renames between directories fail with EXDEV for the rest of the test,
+// as if the staging directory were on another filesystem than the library.
+func crossDeviceRenames(t *testing.T) {
+	t.Cleanup(func() { renameFile = os.Rename })
+	renameFile = func(oldpath, newpath string) error {
+		if filepath.Dir(oldpath) != filepath.Dir(newpath) {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+		}
+		return os.Rename(oldpath, newpath)
+	}
+}
+
+func TestStagingCommitAndRollback(t *testing.T) {
+	root := t.TempDir()
+	AppConfig = defaultConfig()
+	AppConfig.StoragePath = filepath.Join(root, "media")
+	// Drama already holds another movie; Drama/Heat is created by the commit
+	writeFiles(t, filepath.Join(AppConfig.StoragePath, "Drama", "Ronin"), "Ronin.mkv")
+	stage := &stagingArea{Dir: filepath.Join(root, "staging")}
+	files := writeFiles(t, stage.Dir, "Heat {edition-Theatrical Cut}.mkv", "Heat {edition-Extended Cut}.mkv")
+
+	libraryDir := filepath.Join(AppConfig.StoragePath, "Drama", "Heat")
+	moved, err := stage.Commit(files, libraryDir)
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	want := []string{filepath.Join(libraryDir, "Heat {edition-Theatrical Cut}.mkv"), filepath.Join(libraryDir, "Heat {edition-Extended Cut}.mkv")}
+	if !slices.Equal(moved, want) {
+		t.Errorf("Commit = %q, want %q", moved, want)
+	}
+	for _, f := range want {
+		if !exists(f) {
+			t.Errorf("%s not in the library", f)
+		}
+	}
+	if exists(stage.Dir) {
+		t.Error("staging directory not removed after the commit")
+	}
+
+	// Rollback removes what the commit added and nothing else
+	stage.Rollback()
+	if got := treeFiles(t, AppConfig.StoragePath); !slices.Equal(got, []string{"Drama/Ronin/Ronin.mkv"}) {
+		t.Errorf("library after rollback = %q, want only the existing movie", got)
+	}
+	if exists(libraryDir) {
+		t.Error("rollback left the movie folder it created")
+	}
+	if !exists(filepath.Join(AppConfig.StoragePath, "Drama")) {
+		t.Error("rollback removed the category folder that already existed")
+	}
+}
+
+func TestStagingCommitChecksEveryTargetFirst(t *testing.T) {
+	root := t.TempDir()
+	AppConfig = defaultConfig()
+	AppConfig.StoragePath = filepath.Join(root, "media")
+	libraryDir := filepath.Join(AppConfig.StoragePath, "TV", "The Office", "Season 02")
+	writeFiles(t, libraryDir, "The Office - S02E02.mkv")
+	stage := &stagingArea{Dir: filepath.Join(root, "staging")}
+	files := writeFiles(t, stage.Dir, "The Office - S02E01.mkv", "The Office - S02E02.mkv", "The Office - S02E03.mkv")
+
+	moved, err := stage.Commit(files, libraryDir)
+	if err == nil || !strings.Contains(err.Error(), "The Office - S02E02.mkv already exists") {
+		t.Fatalf("Commit error = %v, want the taken name", err)
+	}
+	if len(moved) != 0 {
+		t.Errorf("Commit moved %q before finding the taken name", moved)
+	}
+	for _, f := range files {
+		if !exists(f) {
+			t.Errorf("%s left the staging directory", filepath.Base(f))
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(libraryDir, "The Office - S02E02.mkv")); string(data) != "The Office - S02E02.mkv" {
+		t.Error("existing library file overwritten")
+	}
+
+	// Kept staging areas survive the rollback, the library folder the commit did not create too
+	stage.Keep()
+	stage.Rollback()
+	for _, f := range files {
+		if !exists(f) {
+			t.Errorf("%s removed from a kept staging directory", filepath.Base(f))
+		}
+	}
+	if !exists(libraryDir) {
+		t.Error("rollback removed a season folder it did not create")
+	}
+}
+
+func TestMoveFileAcrossFilesystems(t *testing.T) {
+	crossDeviceRenames(t)
+	root := t.TempDir()
+	src := writeFiles(t, filepath.Join(root, "staging"), "Heat.mkv")[0]
+	dst := filepath.Join(root, "media", "Heat.mkv")
+	os.MkdirAll(filepath.Dir(dst), 0755)
+
+	if err := moveFile(src, dst); err != nil {
+		t.Fatalf("moveFile: %v", err)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "Heat.mkv" {
+		t.Errorf("copied file = %q (%v), want the staged content", data, err)
+	}
+	if exists(src) {
+		t.Error("staged file not removed after the copy")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(dst)); len(entries) != 1 {
+		t.Errorf("library holds %v, want only the moved file", entries)
+	}
+}
+
+func TestMoveFileAcrossFilesystemsNeverOverwrites(t *testing.T) {
+	crossDeviceRenames(t)
+	root := t.TempDir()
+	src := writeFiles(t, filepath.Join(root, "staging"), "Heat.mkv")[0]
+	// Another rip is copying the same name into the library
+	partial := writeFiles(t, filepath.Join(root, "media"), ".Heat.mkv.partial")[0]
+
+	if err := moveFile(src, filepath.Join(root, "media", "Heat.mkv")); err == nil {
+		t.Fatal("moveFile succeeded over another copy in progress")
+	}
+	if !exists(src) {
+		t.Error("staged file removed after a failed copy")
+	}
+	if data, err := os.ReadFile(partial); err != nil || string(data) != ".Heat.mkv.partial" {
+		t.Errorf("the other copy was changed or removed (%q, %v)", data, err)
+	}
+	if exists(filepath.Join(root, "media", "Heat.mkv")) {
+		t.Error("failed copy left a file in the library")
+	}
+}
+
+func TestStagingTargetDir(t *testing.T) {
+	AppConfig = defaultConfig()
+	AppConfig.StoragePath = "/plex/storage"
+	tests := []struct {
+		name       string
+		branch     string
+		libraryDir string
+		want       string
+	}{
+		{"no pool", "", "/plex/storage/Drama/Heat", "/plex/storage/Drama/Heat"},
+		{"same path on the branch", "/mnt/disk2", "/plex/storage/Drama/Heat", "/mnt/disk2/Drama/Heat"},
+		{"pool root", "/mnt/disk2", "/plex/storage", "/mnt/disk2"},
+		{"outside the pool", "/mnt/disk2", "/srv/other/Heat", "/srv/other/Heat"},
+		{"sibling with the same prefix", "/mnt/disk2", "/plex/storage2/Heat", "/plex/storage2/Heat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &stagingArea{Branch: tt.branch}
+			if got := s.targetDir(tt.libraryDir); got != tt.want {
+				t.Errorf("targetDir(%q) = %q, want %q", tt.libraryDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStagingCommitToBranch(t *testing.T) {
+	root := t.TempDir()
+	AppConfig = defaultConfig()
+	AppConfig.StoragePath = filepath.Join(root, "pool")
+	branch := filepath.Join(root, "disk2")
+	stage := &stagingArea{Dir: filepath.Join(branch, stagingDirName, "rip-1"), Branch: branch}
+	files := writeFiles(t, stage.Dir, "Heat.mkv")
+
+	libraryDir := filepath.Join(AppConfig.StoragePath, "Drama", "Heat")
+	moved, err := stage.Commit(files, libraryDir)
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if want := []string{filepath.Join(libraryDir, "Heat.mkv")}; !slices.Equal(moved, want) {
+		t.Errorf("Commit = %q, want the pool path %q", moved, want)
+	}
+	if !exists(filepath.Join(branch, "Drama", "Heat", "Heat.mkv")) {
+		t.Error("file not moved to the same path on the branch")
+	}
+	if exists(libraryDir) {
+		t.Error("Commit wrote to the pool path instead of the branch")
+	}
+
+	stage.Rollback()
+	if exists(filepath.Join(branch, "Drama")) {
+		t.Error("rollback left the folders it created on the branch")
+	}
+}
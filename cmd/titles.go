@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Title selection strategies for rip dvd, selectable with --title-strategy or movie.title_strategy.
+const (
+	titleStrategyLongest      = "longest"       // The longest title
+	titleStrategyLargest      = "largest"       // The title with the most data
+	titleStrategyMostChapters = "most-chapters" // The title with the most chapters
+	titleStrategySegments     = "segments"      // The longest title left after dropping decoy and Play All titles
+	titleStrategyInteractive  = "interactive"   // Ask, suggesting the segments choice
+)
+
+// titleStrategies lists the strategies in the order they are shown in help and errors.
+var titleStrategies = []string{titleStrategyLongest, titleStrategyLargest, titleStrategyMostChapters, titleStrategySegments, titleStrategyInteractive}
+
+// isTitleStrategy reports whether s is one of titleStrategies.
+func isTitleStrategy(s string) bool {
+	return slices.Contains(titleStrategies, s)
+}
+
+// titleChoice is the title picked for a movie rip, with the reason it was picked.
+type titleChoice struct {
+	Title  *TitleInfo
+	Reason string
+}
+
+// selectMovieTitle picks the title of a movie disc to rip.
+// Only titles of at least movie.min_length are considered, since shorter ones are not ripped;
+// readDiscInfo leaves them out, so the title IDs are the ones makemkvcon mkv uses.
+//
+// Parameters:
+//
+//	disc - the disc information returned by readDiscInfo
+//	strategy - one of titleStrategies
+//	titleID - a title chosen with --title; -1 when none was
+//	in - where the interactive strategy reads the answer from
+//	out - where the interactive strategy lists the titles
+//
+// Returns the chosen title and why it was chosen, or an error if no title qualifies,
+// the --title does not exist or is too short, or the strategy is unknown.
+func selectMovieTitle(disc *DiscInfo, strategy string, titleID int, in *os.File, out io.Writer) (*titleChoice, error) {
+	minLength := AppConfig.Movie.MinLength
+	if titleID >= 0 {
+		t := disc.Title(titleID)
+		if t == nil || t.Duration < minLength {
+			return nil, fmt.Errorf("--title %d: the disc has no title %d at least movie.min_length (%s) long; MakeMKV only numbers titles of that length, so lower it with --set movie.min_length=... to rip a shorter title",
+				titleID, titleID, shortDuration(minLength))
+		}
+		return &titleChoice{t, "chosen with --title"}, nil
+	}
+
+	candidates, err := movieCandidates(disc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strategy {
+	case titleStrategyLongest:
+		return &titleChoice{longestOf(candidates), "longest title"}, nil
+	case titleStrategyLargest:
+		largest := candidates[0]
+		for _, t := range candidates[1:] {
+			if t.SizeBytes > largest.SizeBytes || (t.SizeBytes == largest.SizeBytes && t.Duration > largest.Duration) {
+				largest = t
+			}
+		}
+		if largest.SizeBytes <= 0 {
+			return &titleChoice{longestOf(candidates), "longest title (MakeMKV did not report the title sizes)"}, nil
+		}
+		return &titleChoice{largest, "largest title"}, nil
+	case titleStrategyMostChapters:
+		most := candidates[0]
+		for _, t := range candidates[1:] {
+			if t.Chapters > most.Chapters || (t.Chapters == most.Chapters && t.Duration > most.Duration) {
+				most = t
+			}
+		}
+		return &titleChoice{most, fmt.Sprintf("most chapters (%d)", most.Chapters)}, nil
+	case titleStrategySegments:
+		return segmentsChoice(candidates), nil
+	case titleStrategyInteractive:
+		return chooseTitle(candidates, segmentsChoice(candidates), in, out)
+	default:
+		return nil, fmt.Errorf("unknown title strategy %q (use %s)", strategy, strings.Join(titleStrategies, ", "))
+	}
+}
+
+// movieCandidates returns the titles of a disc at least movie.min_length long, in disc order,
+// or an error if there are none.
+func movieCandidates(disc *DiscInfo) ([]*TitleInfo, error) {
+	var candidates []*TitleInfo
+	for _, t := range disc.Titles {
+		if t.Duration >= AppConfig.Movie.MinLength {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no title is at least movie.min_length (%s) long; lower it with --set movie.min_length=... to rip a shorter title", shortDuration(AppConfig.Movie.MinLength))
+	}
+	return candidates, nil
+}
+
+// longestOf returns the longest of titles; of titles of the same length, the one with more chapters.
+func longestOf(titles []*TitleInfo) *TitleInfo {
+	longest := titles[0]
+	for _, t := range titles[1:] {
+		if t.Duration > longest.Duration || (t.Duration == longest.Duration && t.Chapters > longest.Chapters) {
+			longest = t
+		}
+	}
+	return longest
+}
+
+// segmentsChoice picks the longest title left by dropDuplicateTitles.
+func segmentsChoice(candidates []*TitleInfo) *titleChoice {
+	kept, dropped, mapped := dropDuplicateTitles(candidates)
+	choice := &titleChoice{longestOf(kept), "longest title"}
+	if dropped > 0 {
+		choice.Reason = fmt.Sprintf("longest title after skipping %d title(s) with duplicate segments", dropped)
+	} else if !mapped {
+		choice.Reason = "longest title (MakeMKV did not report segment maps)"
+	}
+	return choice
+}
+
+// dropDuplicateTitles rejects the titles whose segment maps duplicate other titles:
+//   - decoys: titles made of the same segments as another title, often in a scrambled order
+//     ("playlist obfuscation"). Of such a group the title playing its segments in disc order is
+//     kept, or else the first one;
+//   - Play All titles: titles containing all the segments of two or more other titles.
+//
+// Every rejected title is printed with the reason. Titles without a segment map are never rejected.
+//
+// Returns the titles kept, in disc order (all of them if every title would be rejected), how many
+// were rejected, and whether any title had a segment map.
+func dropDuplicateTitles(candidates []*TitleInfo) (kept []*TitleInfo, dropped int, mapped bool) {
+	segments := make(map[*TitleInfo][]int, len(candidates))
+	for _, t := range candidates {
+		segments[t] = t.Segments()
+	}
+
+	rejected := map[*TitleInfo]string{}
+	// Decoys: the same set of segments as another title
+	groups := map[string][]*TitleInfo{}
+	var keys []string
+	for _, t := range candidates {
+		if len(segments[t]) == 0 {
+			continue
+		}
+		key := fmt.Sprint(slices.Sorted(slices.Values(segments[t])))
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], t)
+	}
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		keep := group[0]
+		for _, t := range group {
+			if inDiscOrder(segments[t]) {
+				keep = t
+				break
+			}
+		}
+		for _, t := range group {
+			if t == keep {
+				continue
+			}
+			if slices.Equal(segments[t], segments[keep]) {
+				rejected[t] = fmt.Sprintf("same segments as title %d", keep.ID)
+			} else {
+				rejected[t] = fmt.Sprintf("same segments as title %d in a different order (decoy playlist)", keep.ID)
+			}
+		}
+	}
+
+	// Play All: contains every segment of two or more other titles
+	for _, t := range candidates {
+		if _, ok := rejected[t]; ok || len(segments[t]) == 0 {
+			continue
+		}
+		var contained []string
+		for _, other := range candidates {
+			if other == t || len(segments[other]) == 0 || len(segments[other]) >= len(segments[t]) {
+				continue
+			}
+			if _, ok := rejected[other]; ok {
+				continue
+			}
+			if isSubset(segments[other], segments[t]) {
+				contained = append(contained, strconv.Itoa(other.ID))
+			}
+		}
+		if len(contained) >= 2 {
+			rejected[t] = fmt.Sprintf("plays titles %s one after another (Play All)", strings.Join(contained, ", "))
+		}
+	}
+
+	for _, t := range candidates {
+		if reason, ok := rejected[t]; ok {
+			fmt.Printf("Skipping title %d (%s): %s\n", t.ID, formatDuration(t.Duration), reason)
+			continue
+		}
+		kept = append(kept, t)
+	}
+	if len(kept) == 0 {
+		kept = candidates
+	}
+	return kept, len(rejected), len(groups) > 0
+}
+
+// inDiscOrder reports whether segments are played in ascending order.
+func inDiscOrder(segments []int) bool {
+	previous := -1
+	for _, n := range segments {
+		if n <= previous {
+			return false
+		}
+		previous = n
+	}
+	return true
+}
+
+// isSubset reports whether every segment of a is also in b.
+func isSubset(a, b []int) bool {
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// chooseTitle lists the candidate titles on out and lets the user choose one from in.
+// Without a terminal on in, the suggested title is used.
+//
+// Returns the chosen title, or an error if the answer cannot be read.
+func chooseTitle(candidates []*TitleInfo, suggested *titleChoice, in *os.File, out io.Writer) (*titleChoice, error) {
+	if !isTerminal(in) {
+		fmt.Fprintf(out, "Warning: No terminal to ask which title to rip; using title %d (use --title to choose)\n", suggested.Title.ID)
+		return suggested, nil
+	}
+
+	fmt.Fprintln(out, "Titles long enough to be the movie:")
+	def := 1
+	for i, t := range candidates {
+		mark := ""
+		if t == suggested.Title {
+			def = i + 1
+			mark = "  <- suggested: " + suggested.Reason
+		}
+		fmt.Fprintf(out, "  %d) Title %d: %s, %d chapters, %s", i+1, t.ID, formatDuration(t.Duration), t.Chapters, t.Size)
+		if t.Name != "" {
+			fmt.Fprintf(out, ", %q", t.Name)
+		}
+		if t.SegmentMap != "" {
+			fmt.Fprintf(out, ", segments %s", truncate(t.SegmentMap, 40))
+		}
+		fmt.Fprintln(out, mark)
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Fprintf(out, "Select [%d]: ", def)
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if err != nil {
+				fmt.Fprintln(out)
+			}
+			return &titleChoice{candidates[def-1], "chosen interactively"}, nil
+		}
+		choice, convErr := strconv.Atoi(line)
+		if convErr == nil && choice >= 1 && choice <= len(candidates) {
+			return &titleChoice{candidates[choice-1], "chosen interactively"}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection: %s", line)
+		}
+		fmt.Fprintf(out, "Please enter a number from 1 to %d.\n", len(candidates))
+	}
+}
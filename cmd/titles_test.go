@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTitle returns a title with a length in minutes, a segment map and a size in GB.
+func testTitle(id, minutes int, segments string, gb int64) *TitleInfo {
+	return &TitleInfo{ID: id, Duration: time.Duration(minutes) * time.Minute, SegmentMap: segments, SizeBytes: gb << 30, Chapters: minutes / 5}
+}
+
+func TestSelectMovieTitle(t *testing.T) {
+	// A Blu-ray with decoy playlists: the same segments in scrambled orders, one of them a little
+	// longer, a Play All of the movie and its extras, and a bonus feature
+	decoys := &DiscInfo{Titles: []*TitleInfo{
+		testTitle(0, 121, "5,3,1,2,4", 30),
+		testTitle(1, 120, "1,2,3,4,5", 30),
+		testTitle(2, 122, "2,1,3,5,4", 31),
+		testTitle(3, 185, "1,2,3,4,5,6,7,8,9", 45),
+		testTitle(4, 64, "6,7,8,9", 12),
+		testTitle(5, 12, "10", 2),
+	}}
+	tests := []struct {
+		name     string
+		disc     *DiscInfo
+		strategy string
+		titleID  int
+		want     int
+		reason   string
+	}{
+		{"longest", decoys, titleStrategyLongest, -1, 3, "longest title"},
+		{"largest", decoys, titleStrategyLargest, -1, 3, "largest title"},
+		{"most chapters", decoys, titleStrategyMostChapters, -1, 3, "most chapters (37)"},
+		{"segments skips decoys and Play All", decoys, titleStrategySegments, -1, 1, "longest title after skipping 3 title(s) with duplicate segments"},
+		{"--title", decoys, titleStrategySegments, 4, 4, "chosen with --title"},
+		{"segments without segment maps", &DiscInfo{Titles: []*TitleInfo{testTitle(0, 98, "", 0), testTitle(1, 104, "", 0)}},
+			titleStrategySegments, -1, 1, "longest title (MakeMKV did not report segment maps)"},
+		{"largest without sizes", &DiscInfo{Titles: []*TitleInfo{testTitle(0, 98, "", 0), testTitle(1, 104, "", 0)}},
+			titleStrategyLargest, -1, 1, "longest title (MakeMKV did not report the title sizes)"},
+	}
+	AppConfig = defaultConfig()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			choice, err := selectMovieTitle(tt.disc, tt.strategy, tt.titleID, nil, nil)
+			if err != nil {
+				t.Fatalf("selectMovieTitle: %v", err)
+			}
+			if choice.Title.ID != tt.want || choice.Reason != tt.reason {
+				t.Errorf("selectMovieTitle = title %d (%s), want title %d (%s)", choice.Title.ID, choice.Reason, tt.want, tt.reason)
+			}
+		})
+	}
+}
+
+func TestSelectMovieTitleErrors(t *testing.T) {
+	AppConfig = defaultConfig()
+	disc := &DiscInfo{Titles: []*TitleInfo{testTitle(0, 95, "1-20", 8), testTitle(1, 30, "21-25", 2)}}
+	tests := []struct {
+		name     string
+		strategy string
+		titleID  int
+		want     string
+	}{
+		{"missing --title", titleStrategyLongest, 7, "no title 7 at least movie.min_length (1h)"},
+		{"short --title", titleStrategyLongest, 1, "no title 1 at least movie.min_length (1h)"},
+		{"unknown strategy", "shortest", -1, `unknown title strategy "shortest"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := selectMovieTitle(disc, tt.strategy, tt.titleID, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	short := &DiscInfo{Titles: []*TitleInfo{testTitle(0, 45, "1-9", 4)}}
+	if _, err := selectMovieTitle(short, titleStrategyLongest, -1, nil, nil); err == nil {
+		t.Error("selectMovieTitle picked a title shorter than movie.min_length")
+	}
+}
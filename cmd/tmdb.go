@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultTMDBBaseURL is the TMDB v3 API endpoint used when tmdb_base_url is not configured.
+const defaultTMDBBaseURL = "https://api.themoviedb.org/3"
+
+// TMDBClient is a minimal client for The Movie Database (TMDB) v3 API.
+// It covers the calls rip needs: movie and TV search, details and external IDs.
+// BaseURL can point at a local stand-in server for testing.
+type TMDBClient struct {
+	BaseURL    string       // API base URL (e.g. "https://api.themoviedb.org/3")
+	APIKey     string       // v3 API key, or a v4 read access token
+	Language   string       // Response language (e.g. "en-US"); empty uses the TMDB default
+	HTTPClient *http.Client // HTTP client used for requests
+}
+
+// TMDBGenre is a TMDB genre.
+type TMDBGenre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// TMDBMovie is a movie from TMDB search results or movie details.
+// Genres, Runtime and IMDBID are only filled in by MovieDetails.
+type TMDBMovie struct {
+	ID            int         `json:"id"`
+	Title         string      `json:"title"`
+	OriginalTitle string      `json:"original_title"`
+	ReleaseDate   string      `json:"release_date"`
+	Overview      string      `json:"overview"`
+	Popularity    float64     `json:"popularity"`
+	GenreIDs      []int       `json:"genre_ids"`
+	Genres        []TMDBGenre `json:"genres"`
+	Runtime       int         `json:"runtime"`
+	IMDBID        string      `json:"imdb_id"`
+}
+
+// TMDBShow is a TV show from TMDB search results or TV details.
+// Genres and NumberOfSeasons are only filled in by TVDetails.
+type TMDBShow struct {
+	ID              int         `json:"id"`
+	Name            string      `json:"name"`
+	OriginalName    string      `json:"original_name"`
+	FirstAirDate    string      `json:"first_air_date"`
+	Overview        string      `json:"overview"`
+	Popularity      float64     `json:"popularity"`
+	GenreIDs        []int       `json:"genre_ids"`
+	Genres          []TMDBGenre `json:"genres"`
+	NumberOfSeasons int         `json:"number_of_seasons"`
+}
+
+// TMDBExternalIDs holds the IDs other databases use for a TV show.
+// Movies carry their IMDb ID in MovieDetails instead.
+type TMDBExternalIDs struct {
+	IMDBID string `json:"imdb_id"`
+	TVDBID int    `json:"tvdb_id"`
+}
+
+// TMDBSeason is a season of a TV show with its episodes.
+type TMDBSeason struct {
+	SeasonNumber int           `json:"season_number"`
+	Episodes     []TMDBEpisode `json:"episodes"`
+}
+
+// TMDBEpisode is an episode of a TV season.
+type TMDBEpisode struct {
+	EpisodeNumber int    `json:"episode_number"`
+	Name          string `json:"name"`
+	AirDate       string `json:"air_date"`
+}
+
+// tmdbError is the error body TMDB returns for failed requests.
+type tmdbError struct {
+	StatusCode    int    `json:"status_code"`
+	StatusMessage string `json:"status_message"`
+}
+
+// NewTMDBClient returns a client for the public TMDB API using the given API key.
+func NewTMDBClient(apiKey string) *TMDBClient {
+	return &TMDBClient{
+		BaseURL:    defaultTMDBBaseURL,
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// SearchMovie searches TMDB for movies matching query.
+// If year is non-zero, results are limited to movies released that year.
+func (c *TMDBClient) SearchMovie(query string, year int) ([]TMDBMovie, error) {
+	params := url.Values{"query": {query}}
+	if year > 0 {
+		params.Set("year", strconv.Itoa(year))
+	}
+	var resp struct {
+		Results []TMDBMovie `json:"results"`
+	}
+	if err := c.get("/search/movie", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// SearchTV searches TMDB for TV shows matching query.
+// If year is non-zero, results are limited to shows that first aired that year.
+func (c *TMDBClient) SearchTV(query string, year int) ([]TMDBShow, error) {
+	params := url.Values{"query": {query}}
+	if year > 0 {
+		params.Set("first_air_date_year", strconv.Itoa(year))
+	}
+	var resp struct {
+		Results []TMDBShow `json:"results"`
+	}
+	if err := c.get("/search/tv", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// MovieDetails returns the full details of the movie with the given TMDB ID.
+func (c *TMDBClient) MovieDetails(id int) (*TMDBMovie, error) {
+	var movie TMDBMovie
+	if err := c.get(fmt.Sprintf("/movie/%d", id), nil, &movie); err != nil {
+		return nil, err
+	}
+	return &movie, nil
+}
+
+// TVDetails returns the full details of the TV show with the given TMDB ID.
+func (c *TMDBClient) TVDetails(id int) (*TMDBShow, error) {
+	var show TMDBShow
+	if err := c.get(fmt.Sprintf("/tv/%d", id), nil, &show); err != nil {
+		return nil, err
+	}
+	return &show, nil
+}
+
+// TVSeason returns a season of the TV show with the given TMDB ID, with its episodes.
+func (c *TMDBClient) TVSeason(id, season int) (*TMDBSeason, error) {
+	var s TMDBSeason
+	if err := c.get(fmt.Sprintf("/tv/%d/season/%d", id, season), nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// TVExternalIDs returns the IMDb, TheTVDB and other external IDs of a TV show.
+func (c *TMDBClient) TVExternalIDs(id int) (*TMDBExternalIDs, error) {
+	var ids TMDBExternalIDs
+	if err := c.get(fmt.Sprintf("/tv/%d/external_ids", id), nil, &ids); err != nil {
+		return nil, err
+	}
+	return &ids, nil
+}
+
+// get performs a GET request against the API and decodes the JSON response into v.
+// v3 API keys are sent as the api_key parameter; v4 read access tokens (JWTs) as a bearer token.
+func (c *TMDBClient) get(path string, params url.Values, v any) error {
+	if c.APIKey == "" {
+		return fmt.Errorf("TMDB API key is not configured (set metadata.tmdb_api_key in ~/.rip.yaml)")
+	}
+	if params == nil {
+		params = url.Values{}
+	}
+	if c.Language != "" {
+		params.Set("language", c.Language)
+	}
+
+	bearer := strings.HasPrefix(c.APIKey, "eyJ")
+	if !bearer {
+		params.Set("api_key", c.APIKey)
+	}
+
+	reqURL := strings.TrimRight(c.BaseURL, "/") + path + "?" + params.Encode()
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("error creating TMDB request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error querying TMDB: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading TMDB response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr tmdbError
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.StatusMessage != "" {
+			return fmt.Errorf("TMDB request %s failed: %s (HTTP %d)", path, apiErr.StatusMessage, resp.StatusCode)
+		}
+		return fmt.Errorf("TMDB request %s failed: HTTP %d", path, resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error decoding TMDB response: %v", err)
+	}
+	return nil
+}
+
+// releaseYear returns the year from a TMDB date such as "1999-03-30", or 0 if there is none.
+func releaseYear(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	year, _ := strconv.Atoi(date[:4])
+	return year
+}
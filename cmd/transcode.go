@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// transcodeFiles re-encodes staged files with HandBrakeCLI using transcode.preset, replacing each
+// file with its transcoded version. It does nothing when no preset is set.
+// Each file is encoded to a temporary name next to it first, so a failed encode leaves the
+// ripped file as it was.
+//
+// Parameters:
+//
+//	files - the ripped MKV files in the staging directory
+//
+// Returns an error if HandBrakeCLI is missing or an encode fails.
+func transcodeFiles(files []string) error {
+	preset := AppConfig.Transcode.Preset
+	if preset == "" {
+		return nil
+	}
+	if _, err := runner.LookPath("HandBrakeCLI"); err != nil {
+		return fmt.Errorf("transcode.preset is set to %q but HandBrakeCLI is not installed", preset)
+	}
+	for _, file := range files {
+		ext := filepath.Ext(file)
+		out := strings.TrimSuffix(file, ext) + ".transcoding" + ext
+		fmt.Printf("Transcoding %s with HandBrake preset %q...\n", filepath.Base(file), preset)
+		if _, err := runner.Run("HandBrakeCLI", "--preset", preset, "--input", file, "--output", out); err != nil {
+			os.Remove(out)
+			return fmt.Errorf("error transcoding %s: %v", filepath.Base(file), err)
+		}
+		if err := os.Rename(out, file); err != nil {
+			return fmt.Errorf("error replacing %s with its transcoded version: %v", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
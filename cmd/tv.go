@@ -2,14 +2,14 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/rmasci/script"
 	"github.com/spf13/cobra"
 )
 
@@ -17,27 +17,35 @@ import (
 // It automates the process of ripping TV show DVDs using MakeMKV,
 // organizing episodes by season, fetching metadata, and renaming files.
 var tvCmd = &cobra.Command{
-	Use:   "tv [show name] [season-disc]",
-	Short: "Rip TV show DVDs and organize by season",
-	Long:  `Rip TV show DVDs and organize by season. Season-disc format is "season-disc" (e.g., "1-2" for season 1, disc 2).`,
-	Args:  cobra.ExactArgs(2),
-	Run:   tvrip,
+	Use:          "tv [show name] [season-disc]",
+	Short:        "Rip TV show DVDs and organize by season",
+	Long:         `Rip TV show DVDs and organize by season. Season-disc format is "season-disc" (e.g., "1-2" for season 1, disc 2).`,
+	Args:         cobra.ExactArgs(2),
+	RunE:         tvrip,
+	SilenceUsage: true,
 }
 
 // tvrip executes the TV ripping workflow.
 // It performs the following steps:
 // 1. Parses the show name and validates season-disc format
-// 2. Uses FileBot to look up the correct show name and year (with fallback to user input)
+// 2. Looks up the correct show name and year in TMDB (with fallback to user input)
 // 3. Validates the MergerFS mountpoint
-// 4. Creates the output directory structure with CamelCase naming
-// 5. Executes MakeMKV to rip the disc
-// 6. Cleans up files outside the acceptable duration range
-// 7. Renames episodes using FileBot
-// 8. Ejects the disc
-// 9. Displays completion summary
-func tvrip(cmd *cobra.Command, args []string) {
+// 4. Works out the output directory from the naming templates
+// 5. Reads the disc structure with MakeMKV
+// 6. Executes MakeMKV to rip the disc into a staging directory
+// 7. Cleans up files outside the acceptable duration range
+// 8. Names the episodes with the naming templates, numbered after those of earlier discs,
+// transcodes them when transcode.preset is set, moves them into the season folder once verified
+// and records them in the season's disc ledger
+// 9. Ejects the disc and runs the post-rip hooks
+// 10. Displays completion summary
+//
+// Errors are returned rather than exiting, so a failed or cancelled (Ctrl-C, SIGTERM) rip can
+// remove what it created and, with --eject-on-failure, eject the disc.
+func tvrip(cmd *cobra.Command, args []string) (err error) {
 	// Parse command-line flags
-	device, _ := cmd.Flags().GetString("device")
+	progressMode, _ := cmd.Flags().GetString("progress")
+	ejectOnFailure, _ := cmd.Flags().GetBool("eject-on-failure")
 	query := args[0]
 	seasonDiscStr := args[1]
 
@@ -45,209 +53,408 @@ func tvrip(cmd *cobra.Command, args []string) {
 	// This allows users to specify which disc of a multi-disc season they're ripping
 	parts := strings.Split(seasonDiscStr, "-")
 	if len(parts) != 2 {
-		log.Fatal("Error: Invalid season-disc format. You must specify both season and disc (e.g., '1-2' for season 1, disc 2)")
+		return fmt.Errorf("invalid season-disc format. You must specify both season and disc (e.g., '1-2' for season 1, disc 2)")
 	}
 
 	seasonNum := parts[0]
 	discNum := parts[1]
+	season, err1 := strconv.Atoi(seasonNum)
+	discInt, err2 := strconv.Atoi(discNum)
+	if err1 != nil || err2 != nil || season < 0 || discInt < 1 {
+		return fmt.Errorf("invalid season-disc format. Season and disc must be numbers (e.g., '1-2' for season 1, disc 2)")
+	}
+	startEpisode, _ := cmd.Flags().GetInt("start-episode")
+
+	progress, err := newProgressListener(progressMode)
+	if err != nil {
+		return err
+	}
+
+	// Resolve the source (drive, ISO image or disc folder) to a MakeMKV source spec
+	source, err := sourceFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	drive := source.Spec
+	fmt.Printf("Using source: %s\n", source)
+	fmt.Printf("MakeMKV format: %s\n", drive)
+
+	// Lock the drive so no other rip can use it while this one runs
+	var job *Job
+	if source.IsDevice() {
+		job, err = startJob(source.Device, "tv", fmt.Sprintf("%s %s", query, seasonDiscStr))
+		if err != nil {
+			return err
+		}
+		defer job.Finish()
+	}
+
+	// Ctrl-C and SIGTERM cancel the rip; whatever happens, clean up and save the run on the way out
+	ctx, stop := signalContext()
+	defer stop()
+	rollback := newRipRollback(ctx, source, ejectOnFailure)
+	defer func() { err = rollback.Finish(err) }()
+
+	// Record the run in the history, with the output of every command it runs
+	hist := startRunHistory("tv", os.Args[1:], source, job)
+	rollback.hist = hist
 
 	// Step 1: Verify storage path is accessible before proceeding
 	if err := VerifyStoragePath(AppConfig.StoragePath); err != nil {
-		log.Fatalf("Error: %v\n\nPlease edit ~/.rip.conf to set a valid storage_path", err)
+		return fmt.Errorf("%v\n\nPlease edit %s to set a valid storage_path", err, getConfigPath())
 	}
 
-	// Step 2: Try to look up the correct show name using FileBot
+	// Step 2: Try to look up the correct show name
 	// Format: Genre/Show Name (Year) {tmdb-ID}
-	fmt.Printf("Looking up show info in TheTVDB for: %s...\n", query)
-	showPath := fetchMetadata(query, "{genre.toCamelCase()}/{n} ({y}) {tmdb-$id}")
-
-	// If FileBot lookup fails, use a fallback format with the user-provided name
-	if showPath == "" {
-		fmt.Printf("Warning: Could not find show in TheTVDB, using provided name: %s\n", query)
-		// Create a simple fallback path with CamelCase show name
-		showName := toCamelCase(query)
-		showPath = fmt.Sprintf("Unknown/%s", showName)
+	job.SetPhase("looking up metadata")
+	fmt.Printf("Looking up show info in TMDB for: %s...\n", query)
+	match, err := lookupShow(ctx, query, matchOptionsFromFlags(cmd))
+	if err != nil {
+		return err
+	}
+	hist.SetMatch(query, match)
+	showName := query
+	if match == nil {
+		// If the lookup fails, use the user-provided name; the genre is "Unknown"
+		fmt.Printf("Warning: Could not find show in TMDB, using provided name: %s\n", query)
 	} else {
+		showName = match.Title
+	}
+	naming := namingData(match, query)
+	naming.Season = season
+	showPath, err := showFolderPath(naming)
+	if err != nil {
+		return err
+	}
+	if match != nil {
 		fmt.Printf("Found: %s\n", showPath)
 	}
 
-	// Step 3: Create output directory structure with CamelCase naming
-	// Directory format: [StoragePath]/Genre/Show Name (Year)/Season XX/
-	// The showPath from FileBot already has the show name with CamelCase, so we just extract the show name part
-	sPad := fmt.Sprintf("Season %02s", seasonNum)
-	outDir := filepath.Join(AppConfig.StoragePath, showPath, sPad)
-	if err := os.MkdirAll(outDir, 0755); err != nil {
-		log.Fatalf("Error creating output directory: %v", err)
+	// Step 3: Work out the output directory from the naming templates
+	// Directory format: [StoragePath]/[naming.show_folder]/[naming.season_folder]/
+	// The directory is only created once the episodes have been ripped and verified
+	sPad, err := seasonFolderName(season)
+	if err != nil {
+		return err
+	}
+	outDir, err := safeJoin(AppConfig.StoragePath, showPath, sPad)
+	if err != nil {
+		return err
 	}
 
-	// Step 4: Format device path for MakeMKV (handles both Linux and macOS)
-	drive := formatDriveForMakeMKV(device)
-	fmt.Printf("Using device: %s\n", device)
-	fmt.Printf("MakeMKV format: %s\n", drive)
+	// Step 4: Read the disc structure and show which titles look like episodes
+	fmt.Println("Querying disc for available titles...")
+	job.SetPhase("reading disc")
+	disc, err := readDiscInfo(drive, AppConfig.TV.MinEpisodeLength)
+	if err != nil {
+		return fmt.Errorf("error reading disc: %v", err)
+	}
+	hist.SetDisc(disc)
+	naming.Resolution = videoResolution(disc.LongestTitle())
+	printTitles(disc)
+	episodes := 0
+	for _, t := range disc.Titles {
+		if t.Duration >= AppConfig.TV.MinEpisodeLength && t.Duration <= AppConfig.TV.MaxEpisodeLength {
+			episodes++
+		}
+	}
+	fmt.Printf("Disc %s has %d titles in the episode duration range\n", discNum, episodes)
 
-	// Step 5: Execute MakeMKV rip operation
-	fmt.Printf("Ripping to: %s\n", outDir)
-	if err := runTVMakeMKV(drive, outDir); err != nil {
+	// Work out which episode this disc starts with (unless --start-episode was given):
+	// from the disc ledger if earlier discs are recorded there, otherwise from the
+	// episodes already in the season folder
+	ledger, err := loadLedger(outDir, showName, season)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		ledger = &DiscLedger{Show: showName, Season: season}
+	}
+	if previous := ledger.Disc(discInt); previous != nil {
+		fmt.Printf("Warning: Disc %d was already ripped on %s; its ledger entry will be replaced.\n", discInt, previous.RippedAt.Format("2006-01-02"))
+	}
+	if startEpisode < 1 {
+		startEpisode = ledger.NextEpisode(discInt)
+	}
+	if startEpisode < 1 {
+		startEpisode = nextEpisodeNumber(outDir, season)
+		if startEpisode == 1 && discInt > 1 {
+			fmt.Printf("Warning: This is disc %d but no episodes from earlier discs were found in %s.\n", discInt, sPad)
+			fmt.Println("Numbering from episode 1; use --start-episode to set the first episode of this disc.")
+		}
+	}
+	fmt.Printf("Disc %d starts at episode %d\n", discInt, startEpisode)
+
+	// Step 6: Execute MakeMKV rip operation into a staging directory outside the library
+	stage, err := newStagingArea("tv", outDir)
+	if err != nil {
+		return err
+	}
+	rollback.stage = stage
+	release, err := acquireSlot(ctx, job, slotWrite, AppConfig.Limits.WriteJobs)
+	if err != nil {
+		return err
+	}
+	// Fail now rather than part way through the rip if the disc will not fit
+	if err := stage.CheckSpace(tvRipTitles(disc), outDir); err != nil {
+		release()
+		return err
+	}
+	job.SetPhase("ripping")
+	fmt.Printf("Ripping to: %s (staging for %s)\n", stage.Dir, outDir)
+	err = runTVMakeMKV(drive, stage.Dir, progress)
+	release()
+	if err != nil {
 		fmt.Printf("Error during rip: %v\n", err)
-		log.Fatalf("MakeMKV extraction failed. Please check your disc and try again.")
+		return fmt.Errorf("MakeMKV extraction failed (%v). Please check your disc and try again", err)
+	}
+
+	// Step 7: Clean up files that are too short or too long (not episodes)
+	ripped := cleanupPlayAll(stage.Files())
+
+	// Step 8: Name this disc's episodes with naming.episode_file, numbering them from the
+	// start episode, with their titles when the metadata backend knows them
+	var episodeTitles map[int]string
+	if match != nil && len(ripped) > 0 {
+		job.SetPhase("looking up episode titles")
+		episodeTitles = lookupEpisodeTitles(ctx, match, season)
+	}
+	job.SetPhase("renaming")
+	// Unnumbered files would be mistaken for episodes in the season folder, so they stay in staging
+	numbered, err := numberEpisodes(ripped, naming, startEpisode, episodeTitles)
+	if err != nil {
+		stage.Keep()
+		return fmt.Errorf("could not number the episodes: %v\nRename the ripped files and move them into %s, or rip the disc again with --start-episode", err, outDir)
+	}
+
+	// Re-encode the episodes when transcode.preset is set; it counts as a write job like the rip
+	if AppConfig.Transcode.Preset != "" {
+		job.SetPhase("transcoding")
+		release, err := acquireSlot(ctx, job, slotWrite, AppConfig.Limits.WriteJobs)
+		if err != nil {
+			return err
+		}
+		err = transcodeFiles(stage.Files())
+		release()
+		if err != nil {
+			return err
+		}
 	}
 
-	// Step 6: Clean up files that are too short or too long (not episodes)
-	cleanupPlayAll(outDir)
+	// Verify the staged episodes and move them into the season folder,
+	// unless the rip has been cancelled in the meantime
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	job.SetPhase("moving into library")
+	staged := stage.Files()
+	if err := stage.Verify(staged); err != nil {
+		return err
+	}
+	if _, err := stage.Commit(staged, outDir); err != nil {
+		return err
+	}
 
-	// Step 7: Rename episode files with proper names from TheTVDB
-	fmt.Println("Renaming episodes with proper names from FileBot...")
-	if err := renameWithFileBot(seasonNum, discNum, outDir); err != nil {
-		fmt.Printf("Warning: FileBot rename failed: %v\n", err)
+	// Record which episodes this disc produced, so later discs and rip tv status can use it
+	titles := ledgerTitles(disc, ripped, outDir, season, startEpisode)
+	ledger.record(&LedgerDisc{
+		Disc:     discInt,
+		Label:    discLabel(disc),
+		RippedAt: time.Now(),
+		Titles:   titles,
+	})
+	if err := ledger.save(outDir); err != nil {
+		fmt.Printf("Warning: Could not update disc ledger: %v\n", err)
 	}
 
-	// Step 8: Eject the disc from the drive
-	devicePath := extractDevicePath(drive)
-	if err := ejectDisc(devicePath); err != nil {
-		fmt.Printf("Warning: Could not eject disc: %v\n", err)
+	// Step 9: Eject the disc from the drive
+	// Images and folders have nothing to eject
+	if source.IsDevice() {
+		if err := ejectDisc(source.Device); err != nil {
+			fmt.Printf("Warning: Could not eject disc: %v\n", err)
+		}
 	}
 
-	// Step 9: Display completion summary with next steps
+	runPostRipHooks("tv", outDir, showName)
+
+	// Step 10: Display completion summary with next steps
 	fmt.Println("-------------------------------------------------------")
 	fmt.Println("RIP COMPLETE!")
 	fmt.Printf("Files are in: %s\n", outDir)
-	fmt.Printf("Step 1: Verify episodes match S%sE01, S%sE02, etc.\n", sPad, sPad)
+	fmt.Printf("Step 1: Verify episodes match S%02dE01, S%02dE02, etc.\n", season, season)
 	fmt.Println("Step 2: Verify file names are correct.")
 	fmt.Println("Step 3: Scan library in Jellyfin/Plex Dashboard.")
+
+	var files []string
+	for _, t := range titles {
+		hist.AddTitle(disc.Title(t.TitleID), "")
+		if t.File != "" {
+			files = append(files, filepath.Join(outDir, t.File))
+		}
+	}
+	if len(files) == 0 {
+		files = numbered
+	}
+	hist.SetOutput(outDir, files)
+	return nil
 }
 
 // cleanupPlayAll removes MKV files that are outside the acceptable duration range for TV episodes.
 // It removes files that are:
-// - Shorter than 10 minutes (600 seconds) - likely intro/outro files
-// - Longer than 1 hour 5 minutes (3900 seconds) - likely "Play All" merged tracks
+// - Shorter than tv.min_episode_length (10 minutes by default) - likely intro/outro files
+// - Longer than tv.max_episode_length (65 minutes by default) - likely "Play All" merged tracks
 //
 // The function uses ffprobe to determine file duration and requires it to be installed.
 // If ffprobe is not available, it logs a warning and skips cleanup.
-func cleanupPlayAll(dir string) {
+//
+// Returns the files that were kept.
+func cleanupPlayAll(files []string) []string {
 	fmt.Println("Cleaning up extra-long 'Play All' tracks...")
 
 	// Check if ffprobe is available before attempting to use it
-	if _, err := exec.LookPath("ffprobe"); err != nil {
+	if _, err := runner.LookPath("ffprobe"); err != nil {
 		fmt.Println("Warning: ffprobe not found. Skipping automatic Play-All cleanup.")
-		return
+		return files
 	}
 
-	// Get all MKV files in the output directory
-	files, _ := filepath.Glob(filepath.Join(dir, "*.mkv"))
-
-	// Define acceptable episode duration range
-	minSeconds := 600  // 10 minutes - minimum episode length
-	maxSeconds := 3900 // 1 hour 5 minutes - maximum episode length
+	// Define acceptable episode duration range (tv.min_episode_length and tv.max_episode_length)
+	minSeconds := int(AppConfig.TV.MinEpisodeLength.Seconds()) // 10 minutes by default - minimum episode length
+	maxSeconds := int(AppConfig.TV.MaxEpisodeLength.Seconds()) // 1 hour 5 minutes by default - maximum episode length
 
+	var kept []string
 	for _, f := range files {
 		// Get duration via ffprobe in seconds
-		out, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", f).Output()
+		out, err := runner.Run("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", f)
 		if err != nil {
+			kept = append(kept, f)
 			continue
 		}
 
 		// Parse duration string to float
-		durationStr := strings.TrimSpace(string(out))
+		durationStr := strings.TrimSpace(out)
 		duration, _ := strconv.ParseFloat(durationStr, 64)
 		durationInt := int(duration)
 
 		// Remove files that are too short (likely not actual episodes)
 		if durationInt < minSeconds {
-			fmt.Printf("Removing file shorter than 10 minutes: %s (Duration: %.2f min)\n", filepath.Base(f), duration/60)
+			fmt.Printf("Removing file shorter than tv.min_episode_length (%s): %s (Duration: %.2f min)\n", shortDuration(AppConfig.TV.MinEpisodeLength), filepath.Base(f), duration/60)
 			if err := os.Remove(f); err != nil {
 				fmt.Printf("Warning: Could not remove file %s: %v\n", filepath.Base(f), err)
 			}
 		} else if durationInt > maxSeconds {
 			// Remove files that are too long (likely "Play All" or merged tracks)
-			fmt.Printf("Removing file longer than 1 hour 5 minutes: %s (Duration: %.2f min)\n", filepath.Base(f), duration/60)
+			fmt.Printf("Removing file longer than tv.max_episode_length (%s): %s (Duration: %.2f min)\n", shortDuration(AppConfig.TV.MaxEpisodeLength), filepath.Base(f), duration/60)
 			if err := os.Remove(f); err != nil {
 				fmt.Printf("Warning: Could not remove file %s: %v\n", filepath.Base(f), err)
 			}
+		} else {
+			kept = append(kept, f)
 		}
 	}
+	return kept
+}
+
+// episodeNumberPattern matches the season and episode in names like "Show - S01E05 - Title.mkv".
+var episodeNumberPattern = regexp.MustCompile(`(?i)S(\d{1,2})E(\d{1,3})`)
+
+// nextEpisodeNumber returns the episode number the next disc of a season starts with:
+// one past the highest episode of that season already in dir, or 1 if there is none.
+func nextEpisodeNumber(dir string, season int) int {
+	highest := 0
+	for _, f := range listMKVFiles(dir) {
+		m := episodeNumberPattern.FindStringSubmatch(filepath.Base(f))
+		if m == nil {
+			continue
+		}
+		s, _ := strconv.Atoi(m[1])
+		e, _ := strconv.Atoi(m[2])
+		if s == season && e > highest {
+			highest = e
+		}
+	}
+	return highest + 1
+}
+
+// listMKVFiles returns the MKV files in dir, sorted by name.
+func listMKVFiles(dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.mkv"))
+	sort.Strings(files)
+	return files
+}
+
+// numberEpisodes names freshly ripped files with naming.episode_file (by default
+// "Show - SxxEyy - Title.mkv"), numbering them in title order starting at startEpisode.
+// MakeMKV names files after the title index (e.g. "title_t03.mkv"), so sorting by name
+// keeps the disc order.
+//
+// Parameters:
+//
+//	files - the ripped episode files
+//	naming - the naming data of the show, with the season set
+//	startEpisode - the episode number of the first file
+//	titles - episode titles keyed by episode number; may be nil
+//
+// Returns the renamed file paths, or an error if a target name already exists or a rename fails.
+func numberEpisodes(files []string, naming NamingData, startEpisode int, titles map[int]string) ([]string, error) {
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+
+	var numbered []string
+	for i, f := range sorted {
+		naming.Episode = startEpisode + i
+		naming.EpisodeTitle = titles[naming.Episode]
+		base, err := episodeFileName(naming)
+		if err != nil {
+			return numbered, err
+		}
+		name := base + ".mkv"
+		target := filepath.Join(filepath.Dir(f), name)
+		if _, err := os.Stat(target); err == nil {
+			return numbered, fmt.Errorf("%s already exists", name)
+		}
+		if err := os.Rename(f, target); err != nil {
+			return numbered, fmt.Errorf("error renaming %s to %s: %v", filepath.Base(f), name, err)
+		}
+		fmt.Printf("Named: %s -> %s\n", filepath.Base(f), name)
+		numbered = append(numbered, target)
+	}
+	return numbered, nil
 }
 
 // init registers the tv command with the root command and configures its flags.
 func init() {
 	// Define the device flag for specifying the DVD drive location
-	tvCmd.Flags().StringP("device", "d", "/dev/sr0", "Physical device path")
+	tvCmd.Flags().StringP("device", "d", "", "Physical device path (default: device from the config)")
+	tvCmd.Flags().String("source", "", "Rip from an image or folder instead of the device (iso:/path/file.iso, file:/path/VIDEO_TS)")
+	tvCmd.Flags().String("progress", "bar", "Rip progress output: bar, log, json or none")
+	tvCmd.Flags().Bool("eject-on-failure", false, "Eject the disc when the rip fails or is cancelled")
+	tvCmd.Flags().Int("start-episode", 0, "Episode number of the first episode on this disc (default: continue after the episodes already ripped)")
+	addMatchFlags(tvCmd)
 
 	// Register the tv command as a subcommand of the root command
 	rootCmd.AddCommand(tvCmd)
 }
 
 // runTVMakeMKV executes the MakeMKV command to rip all titles from a TV show disc.
-// It uses the script library to execute the makemkvcon CLI tool with appropriate parameters.
+// Progress is reported to the given listener while the titles are extracted.
 //
 // Parameters:
 //
 //	drive - the disc specification (e.g., "disc:0")
 //	outDir - the output directory where MKV files will be saved
+//	progress - receives progress events while the disc is ripped; may be nil
 //
 // Returns an error if the makemkvcon command fails.
-func runTVMakeMKV(drive, outDir string) error {
-	// Execute makemkvcon mkv command to rip all titles longer than 10 minutes (600 seconds)
+func runTVMakeMKV(drive, outDir string, progress ProgressListener) error {
+	// Execute makemkvcon mkv command to rip all titles of at least tv.min_episode_length (10 minutes by default)
 	// Parameters:
 	//   mkv - operation to rip to Matroska format
 	//   drive - disc specification
 	//   all - rip all titles from the disc
 	//   outDir - destination folder for output files
-	//   --minlength=600 - only rip titles longer than 10 minutes
-	return script.Exec(fmt.Sprintf("makemkvcon mkv %s all \"%s\" --minlength=600", drive, outDir)).
-		Spinner("Extracting episodes...", 1).
-		Error()
-}
-
-// renameWithFileBot uses FileBot to rename episode files with proper names from TheTVDB database.
-// It renames files recursively in the output directory using a Plex-compatible naming format.
-//
-// Parameters:
-//
-//	seasonNum - the season number (e.g., "1" for season 1)
-//	discNum - the disc number within the season (e.g., "2" for disc 2)
-//	outDir - the directory containing the episode files to rename
-//
-// The format string produces names like: "Show Name - S01E01 - Episode Title"
-// The discNum parameter is parsed but currently not actively used in the episode offset calculation.
-//
-// Returns an error if the FileBot rename command fails.
-func renameWithFileBot(seasonNum, discNum, outDir string) error {
-	// FileBot rename command format:
-	// filebot -rename "source_folder" -r --db TheTVDB --format "format_string"
-	// The format string uses Plex-compatible naming: {n} S{s}E{e} - {t}
-	// where: n=show name, s=season, e=episode, t=episode title
-
-	// Parse disc number from string to integer
-	// This helps FileBot understand which episodes are on this disc
-	// For example: Disc 1 has episodes 1-4, Disc 2 has episodes 5-8, etc.
-	discInt := 1
-	if discNum != "" {
-		_, _ = fmt.Sscanf(discNum, "%d", &discInt)
-	}
-
-	// Format with season information in output
-	// Example output: "Show Name - S01E01 - Episode Title"
-	renameFormat := "{n} - S" + seasonNum + "E{e} - {t}"
-
-	// Execute FileBot rename command with --action move to actually rename files
-	fmt.Println("Running FileBot to rename episode files...")
-	cmd := fmt.Sprintf("filebot -rename \"%s\" -r --db TheTVDB --format '%s' --action move", outDir, renameFormat)
-	fmt.Printf("FileBot command: %s\n", cmd)
-
-	p := script.Exec(cmd).
-		Spinner("Renaming episodes...", 1)
-	output, err := p.String()
-
-	// Always print the output for debugging
-	if output != "" {
-		fmt.Printf("FileBot output:\n%s\n", output)
-	}
-
+	//   --minlength - only rip titles of at least tv.min_episode_length
+	output, err := runMakeMKVWithProgress([]string{"mkv", drive, "all", outDir, minLengthFlag(AppConfig.TV.MinEpisodeLength)}, progress)
 	if err != nil {
-		fmt.Printf("FileBot error: %v\n", err)
-		// Don't return error - FileBot might succeed even if script.Exec returns an error
-		return nil
+		fmt.Printf("MakeMKV error output:\n%s\n", output)
+		return fmt.Errorf("makemkvcon mkv command failed: %v", err)
 	}
-
 	return nil
 }
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// tvStatusCmd represents the `tv status` command, which shows the ripped and missing discs of a season.
+var tvStatusCmd = &cobra.Command{
+	Use:   "status [show name] [season]",
+	Short: "Show which discs of a season have been ripped",
+	Long: `Show which discs of a TV season have been ripped and which episodes each produced,
+based on the disc ledger rip tv keeps in every season folder.
+
+Discs missing between the first and the last ripped disc are listed as missing.
+Use --discs to give the number of discs in the season so missing discs at the end are listed too.`,
+	Args: cobra.ExactArgs(2),
+	Run:  tvStatus,
+}
+
+// tvStatus prints the disc ledger of every matching show for the requested season.
+func tvStatus(cmd *cobra.Command, args []string) {
+	totalDiscs, _ := cmd.Flags().GetInt("discs")
+	query := args[0]
+	season, err := strconv.Atoi(args[1])
+	if err != nil || season < 0 {
+		log.Fatalf("Error: Invalid season %q. The season must be a number (e.g., 1)", args[1])
+	}
+
+	showDirs := findShowDirs(AppConfig.StoragePath, query)
+	if len(showDirs) == 0 {
+		log.Fatalf("Error: No show matching %q found in %s", query, AppConfig.StoragePath)
+	}
+
+	seasonFolder, err := seasonFolderName(season)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	for _, showDir := range showDirs {
+		seasonDir := filepath.Join(showDir, seasonFolder)
+		rel, _ := filepath.Rel(AppConfig.StoragePath, seasonDir)
+		fmt.Printf("%s\n", rel)
+
+		if _, err := os.Stat(seasonDir); err != nil {
+			fmt.Println("  No discs ripped for this season yet.")
+			continue
+		}
+		ledger, err := loadLedger(seasonDir, filepath.Base(showDir), season)
+		if err != nil {
+			fmt.Printf("  Warning: %v\n", err)
+			continue
+		}
+		if len(ledger.Discs) == 0 {
+			fmt.Printf("  No disc ledger found (%d episode files in the folder).\n", len(listMKVFiles(seasonDir)))
+			continue
+		}
+
+		for _, d := range ledger.Discs {
+			fmt.Printf("  Disc %d: %s, ripped %s\n", d.Disc, episodeRange(d), d.RippedAt.Format("2006-01-02 15:04"))
+			if d.Label != "" {
+				fmt.Printf("    Label: %s\n", d.Label)
+			}
+			for _, t := range d.Titles {
+				fmt.Printf("    E%02d  title %-3d %-10s %s\n", t.Episode, t.TitleID, t.Duration, t.File)
+			}
+		}
+
+		missing := ledger.MissingDiscs(totalDiscs)
+		if len(missing) == 0 {
+			fmt.Println("  Missing discs: none")
+		} else {
+			numbers := make([]string, len(missing))
+			for i, n := range missing {
+				numbers[i] = strconv.Itoa(n)
+			}
+			fmt.Printf("  Missing discs: %s\n", strings.Join(numbers, ", "))
+		}
+	}
+}
+
+// findShowDirs returns the show directories under the storage path whose name contains query.
+// Shows are stored as [StoragePath]/[naming.show_folder], by default Genre/Show Name (Year) {tmdb-ID},
+// so only the level of the show folders is searched.
+func findShowDirs(storagePath, query string) []string {
+	pattern := []string{storagePath}
+	for range showFolderDepth() {
+		pattern = append(pattern, "*")
+	}
+	dirs, _ := filepath.Glob(filepath.Join(pattern...))
+	needle := strings.ToLower(query)
+	var matches []string
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if strings.Contains(strings.ToLower(filepath.Base(dir)), needle) {
+			matches = append(matches, dir)
+		}
+	}
+	return matches
+}
+
+// episodeRange describes the episodes of a ledger disc, e.g. "episodes 5-8".
+func episodeRange(d *LedgerDisc) string {
+	switch len(d.Titles) {
+	case 0:
+		return "no episodes"
+	case 1:
+		return fmt.Sprintf("episode %d", d.Titles[0].Episode)
+	}
+	return fmt.Sprintf("episodes %d-%d", d.Titles[0].Episode, d.Titles[len(d.Titles)-1].Episode)
+}
+
+// init registers the tv status command with the tv command.
+func init() {
+	tvCmd.AddCommand(tvStatusCmd)
+	tvStatusCmd.Flags().Int("discs", 0, "Number of discs in the season, to list missing discs after the last ripped one")
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// probeDurations returns a Do function for makemkvcon mkv that rips like ripTitle and scripts
+// ffprobe to report the given duration (in seconds) for each ripped title, and a valid duration
+// for the files once they are renamed.
+func probeDurations(f *FakeRunner, seconds map[int]string) func(args []string) error {
+	ids := make([]int, 0, len(seconds))
+	for id := range seconds {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	rip := ripTitle(ids...)
+	return func(args []string) error {
+		for _, id := range ids {
+			name := filepath.Join(args[5], fmt.Sprintf("title_t%02d.mkv", id))
+			f.On("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", name).Return(seconds[id]+"\n", nil)
+		}
+		f.On("ffprobe").Return("1300.0\n", nil)
+		return rip(args)
+	}
+}
+
+func TestTVRip(t *testing.T) {
+	env := newRipEnv(t)
+	// Two episodes and a Play All title; MakeMKV skips the menu loop below tv.min_episode_length
+	env.Fake.On("makemkvcon", "-r", "info").Return(robotInfo("0:22:31", "0:23:02", "1:15:33", "0:00:40"), nil)
+	env.Fake.On("makemkvcon", "-r", "--progress=-same", "mkv").Do(probeDurations(env.Fake, map[int]string{0: "1351.2", 1: "1382.0", 2: "4533.4"}))
+	env.Fake.On("eject").Return("", nil)
+
+	if err := env.run(t, "tv", "--device", "/dev/sr0", "--progress", "none", "--", "Test Show", "1-1"); err != nil {
+		t.Fatalf("rip tv: %v", err)
+	}
+
+	if got, want := findCall(env.Fake, "makemkvcon", "-r", "info"), []string{"makemkvcon", "-r", "info", "disc:0", "--minlength=600"}; !slices.Equal(got, want) {
+		t.Errorf("info command = %q, want %q", got, want)
+	}
+	mkv := findCall(env.Fake, "makemkvcon", "-r", "--progress=-same", "mkv")
+	if len(mkv) != 8 {
+		t.Fatalf("mkv command = %q, want 8 arguments", mkv)
+	}
+	if want := []string{"makemkvcon", "-r", "--progress=-same", "mkv", "disc:0", "all", mkv[6], "--minlength=600"}; !slices.Equal(mkv, want) {
+		t.Errorf("mkv command = %q, want %q", mkv, want)
+	}
+	if env.Fake.Called("eject", "/dev/sr0") != 1 {
+		t.Errorf("eject /dev/sr0 called %d times, want once", env.Fake.Called("eject"))
+	}
+
+	want := []string{
+		"Unknown/Test Show/Season 01/.rip-ledger.json",
+		"Unknown/Test Show/Season 01/Test Show - S01E01.mkv",
+		"Unknown/Test Show/Season 01/Test Show - S01E02.mkv",
+	}
+	if got := treeFiles(t, env.Storage); !slices.Equal(got, want) {
+		t.Errorf("library = %q, want %q", got, want)
+	}
+	assertStagingEmpty(t, env.Storage)
+}
+
+func TestTVRipRollsBackFailedRip(t *testing.T) {
+	env := newRipEnv(t)
+	env.Fake.Missing["ffprobe"] = true
+	env.Fake.On("makemkvcon", "-r", "info").Return(robotInfo("0:22:31", "0:23:02"), nil)
+	// The first episode is written before makemkvcon fails
+	env.Fake.On("makemkvcon", "-r", "--progress=-same", "mkv").Do(ripTitle(0)).Return("", errors.New("exit status 1"))
+	env.Fake.On("eject").Return("", nil)
+
+	if err := env.run(t, "tv", "--device", "/dev/sr0", "--progress", "none", "--", "Test Show", "1-1"); err == nil {
+		t.Fatal("rip tv succeeded, want an error")
+	}
+	if got := treeFiles(t, env.Storage); len(got) > 0 {
+		t.Errorf("failed rip left files in the library: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(env.Storage, "Unknown")); !os.IsNotExist(err) {
+		t.Errorf("failed rip left the show folder behind (%v)", err)
+	}
+	assertStagingEmpty(t, env.Storage)
+	if got := env.Fake.Called("eject"); got != 0 {
+		t.Errorf("eject called %d times after a failed rip, want 0", got)
+	}
+}
+
+func TestTVRipKeepsUnnumberedEpisodesInStaging(t *testing.T) {
+	env := newRipEnv(t)
+	env.Fake.Missing["ffprobe"] = true
+	env.Fake.On("makemkvcon", "-r", "info").Return(robotInfo("0:22:31", "0:23:02"), nil)
+	// A file left with the name the second episode gets makes numbering fail
+	env.Fake.On("makemkvcon", "-r", "--progress=-same", "mkv").Do(func(args []string) error {
+		if err := ripTitle(0, 1)(args); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(args[5], "x Test Show S01E02.mkv"), []byte("matroska"), 0644)
+	})
+	env.Fake.On("eject").Return("", nil)
+
+	err := env.run(t, "tv", "--device", "/dev/sr0", "--progress", "none", "--set", "naming.episode_file=x {{.Title}} S{{pad .Season 2}}E{{pad .Episode 2}}", "--", "Test Show", "1-1")
+	if err == nil {
+		t.Fatal("rip tv succeeded, want an error")
+	}
+	if got := treeFiles(t, env.Storage); len(got) > 0 {
+		t.Errorf("unnumbered episodes were moved into the library: %q", got)
+	}
+	staged, _ := filepath.Glob(filepath.Join(env.Storage, stagingDirName, "*", "*.mkv"))
+	if len(staged) != 3 {
+		t.Errorf("staging holds %q, want the ripped files kept", staged)
+	}
+	if got := env.Fake.Called("eject"); got != 0 {
+		t.Errorf("eject called %d times after a failed rip, want 0", got)
+	}
+}
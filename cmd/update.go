@@ -4,12 +4,10 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
 
-	"github.com/rmasci/script"
 	"github.com/spf13/cobra"
 )
 
@@ -103,8 +101,7 @@ func updateMakeMKV(_ *cobra.Command, _ []string) {
 
 // getLatestMakeMKVVersion fetches the latest version number from the MakeMKV website
 func getLatestMakeMKVVersion() string {
-	p := script.Exec("curl -s https://www.makemkv.com/download/")
-	out, err := p.String()
+	out, err := runner.Run("curl", "-s", "https://www.makemkv.com/download/")
 	if err != nil {
 		return ""
 	}
@@ -121,8 +118,7 @@ func getLatestMakeMKVVersion() string {
 
 // isMakeMKVVersionInstalled checks if the specified version is already installed
 func isMakeMKVVersionInstalled(version string) bool {
-	p := script.Exec("makemkvcon info disc:0 2>&1")
-	out, err := p.String()
+	out, err := runner.Run("makemkvcon", "info", "disc:0")
 	if err != nil {
 		return false
 	}
@@ -139,8 +135,7 @@ func downloadFile(url, filepath string) error {
 		return nil
 	}
 
-	cmd := exec.Command("wget", "-q", url, "-O", filepath)
-	if err := cmd.Run(); err != nil {
+	if _, err := runner.Run("wget", "-q", url, "-O", filepath); err != nil {
 		return fmt.Errorf("wget failed: %v", err)
 	}
 
@@ -150,8 +145,7 @@ func downloadFile(url, filepath string) error {
 // buildMakeMKV extracts and builds MakeMKV from a tar.gz file
 func buildMakeMKV(workDir, tarFile, pkgName string) error {
 	// Extract tar file
-	cmd := exec.Command("tar", "xzf", tarFile, "-C", workDir)
-	if err := cmd.Run(); err != nil {
+	if _, err := runner.Run("tar", "xzf", tarFile, "-C", workDir); err != nil {
 		return fmt.Errorf("tar extraction failed: %v", err)
 	}
 
@@ -173,21 +167,20 @@ func buildMakeMKV(workDir, tarFile, pkgName string) error {
 		return fmt.Errorf("could not find extracted directory for %s", pkgName)
 	}
 
-	// Configure
-	configCmd := exec.Command("bash", "-c", "cd "+buildDir+" && ./configure")
-	if err := configCmd.Run(); err != nil {
+	// Configure, build and install in the extracted directory; the directory name comes from
+	// the downloaded archive, so it is never put into a shell command
+	build := runnerInDir(runner, buildDir)
+	if _, err := build.Run("./configure"); err != nil {
 		return fmt.Errorf("configure failed: %v", err)
 	}
 
 	// Build
-	makeCmd := exec.Command("bash", "-c", "cd "+buildDir+" && make")
-	if err := makeCmd.Run(); err != nil {
+	if _, err := build.Run("make"); err != nil {
 		return fmt.Errorf("make failed: %v", err)
 	}
 
 	// Install (with sudo)
-	installCmd := exec.Command("bash", "-c", "cd "+buildDir+" && sudo make install")
-	if err := installCmd.Run(); err != nil {
+	if _, err := build.Run("sudo", "make", "install"); err != nil {
 		return fmt.Errorf("make install failed: %v", err)
 	}
 
@@ -196,8 +189,7 @@ func buildMakeMKV(workDir, tarFile, pkgName string) error {
 
 // verifyMakeMKVInstallation checks if MakeMKV is working
 func verifyMakeMKVInstallation() error {
-	p := script.Exec("makemkvcon info disc:0 2>&1")
-	out, err := p.String()
+	out, err := runner.Run("makemkvcon", "info", "disc:0")
 	if err != nil {
 		return fmt.Errorf("makemkvcon verification failed: %v", err)
 	}
@@ -0,0 +1,339 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// watchCmd represents the `watch` command, which rips discs automatically when they are inserted.
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Rip discs automatically when they are inserted",
+	Long: `Watch the optical drives and rip every disc that is inserted, without anyone at the keyboard.
+
+Drive state is polled through MakeMKV. When a disc appears, its titles are read and the disc is
+classified as a movie or a TV disc from the title durations. The first matching entry of the
+watch queue (see rip watch queue) decides how it is ripped; movies without a queue entry go into
+the --category folder, TV discs without a queue entry are skipped because the show is unknown.
+
+The rip itself runs the normal dvd or tv workflow, which ejects the disc when it is done.
+Every drive is handled on its own, so discs in several drives are ripped in parallel, each rip
+holding the lock on its drive (see rip jobs). The lock is also held while the disc is read and
+classified. Drives already in use by another rip are left alone.
+
+With --once, the discs already in the drives are ripped in parallel and rip watch exits when
+all of them are done, e.g. to rip a batch of discs loaded into every drive.`,
+	Args: cobra.NoArgs,
+	Run:  runWatch,
+}
+
+// Disc kinds returned by classifyDisc. They match the rip request types used by the queue.
+const (
+	discKindMovie   = "dvd"
+	discKindTV      = "tv"
+	discKindUnknown = ""
+)
+
+// watcher holds the state of a running rip watch.
+type watcher struct {
+	category   string        // Category for movies without a queue entry; empty skips them
+	interval   time.Duration // How often drive state is polled
+	executable string        // The rip binary run for each disc
+
+	mu      sync.Mutex
+	handled map[int]bool // Drives whose current disc has been handled (ripped, failed or skipped)
+	wg      sync.WaitGroup
+}
+
+// runWatch polls the drives until interrupted.
+func runWatch(cmd *cobra.Command, args []string) {
+	category, _ := cmd.Flags().GetString("category")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	once, _ := cmd.Flags().GetBool("once")
+
+	if category != "" {
+		if err := validateCategoryName(category); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	if interval < time.Second {
+		log.Fatalf("Error: --interval must be at least 1s")
+	}
+	if _, err := runner.LookPath("makemkvcon"); err != nil {
+		log.Fatalf("Error: makemkvcon not found in PATH")
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Error finding the rip executable: %v", err)
+	}
+
+	w := &watcher{
+		category:   category,
+		interval:   interval,
+		executable: executable,
+		handled:    map[int]bool{},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if category == "" {
+		fmt.Println("No --category set: movies are only ripped when they have a queue entry")
+	}
+	if once {
+		// Rip the discs that are in the drives now, one rip per drive
+		fmt.Println("Ripping the discs in the drives")
+		w.poll()
+		w.wg.Wait()
+		return
+	}
+	fmt.Printf("Watching drives every %s (Ctrl+C to stop)\n", interval)
+	w.run(ctx)
+
+	fmt.Println("Waiting for running rips to finish...")
+	w.wg.Wait()
+}
+
+// run polls the drives until ctx is cancelled.
+func (w *watcher) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.poll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads the drive states and starts handling every newly inserted disc.
+func (w *watcher) poll() {
+	drives, err := listDrives()
+	if err != nil {
+		fmt.Printf("Warning: Could not read drive state: %v\n", err)
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, d := range drives {
+		if !d.HasDisc() {
+			// The disc was removed (or ejected after its rip); the next disc is new
+			delete(w.handled, d.Index)
+			continue
+		}
+		if w.handled[d.Index] {
+			continue
+		}
+		w.handled[d.Index] = true
+		if d.Device != "" && driveInUse(d.Device) {
+			// Another rip (e.g. a rip dvd started by hand) owns the drive and its disc
+			fmt.Printf("[%s] Drive is in use by another rip (see rip jobs); leaving this disc alone\n", driveLabel(d))
+			continue
+		}
+		w.wg.Add(1)
+		go func(d DriveInfo) {
+			defer w.wg.Done()
+			w.handle(d)
+		}(d)
+	}
+}
+
+// listDrives returns the drives MakeMKV reports and their state.
+// Asking for a drive index that does not exist makes makemkvcon list the drives and exit
+// without opening a disc, which keeps polling cheap.
+func listDrives() ([]DriveInfo, error) {
+	out, err := runner.Run("makemkvcon", "-r", "--cache=1", "info", "disc:9999")
+	disc, parseErr := ParseMakeMKVInfo(strings.NewReader(out))
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	if len(disc.Drives) == 0 && err != nil {
+		return nil, err
+	}
+	return disc.Drives, nil
+}
+
+// handle classifies the disc in a drive and rips it according to the queue.
+func (w *watcher) handle(d DriveInfo) {
+	name := driveLabel(d)
+	fmt.Printf("[%s] Disc inserted: %s\n", name, d.DiscName)
+
+	spec := fmt.Sprintf("disc:%d", d.Index)
+	device := d.Device
+	if device == "" {
+		device = extractDevicePath(spec)
+	}
+
+	// Hold the drive while the disc is read and classified, so a rip started by hand cannot
+	// take the drive in the meantime. The lock is released just before the rip starts; the rip
+	// takes it again itself and fails cleanly if another rip got there first.
+	job, err := startJob(device, "watch", d.DiscName)
+	if err != nil {
+		fmt.Printf("[%s] %v; leaving this disc alone\n", name, err)
+		return
+	}
+	defer job.Finish()
+	job.SetPhase("reading disc")
+
+	// Read the disc with the shortest --minlength either rip uses, so the titles classified are
+	// the ones the rip sees (MakeMKV numbers only the titles at least that long)
+	out, err := runner.Run("makemkvcon", "-r", "info", spec, minLengthFlag(min(AppConfig.TV.MinEpisodeLength, AppConfig.Movie.MinLength)))
+	disc, parseErr := ParseMakeMKVInfo(strings.NewReader(out))
+	if parseErr != nil || len(disc.Titles) == 0 {
+		if cause := firstError(parseErr, err); cause != nil {
+			fmt.Printf("[%s] Could not read the disc titles (%v); skipping this disc\n", name, cause)
+		} else {
+			fmt.Printf("[%s] No titles found on the disc; skipping it\n", name)
+		}
+		return
+	}
+
+	kind, reason := classifyDisc(disc)
+	switch kind {
+	case discKindMovie:
+		fmt.Printf("[%s] Looks like a movie: %s\n", name, reason)
+	case discKindTV:
+		fmt.Printf("[%s] Looks like a TV disc: %s\n", name, reason)
+	default:
+		fmt.Printf("[%s] Could not tell whether this is a movie or a TV disc: %s\n", name, reason)
+	}
+
+	entry, err := takeQueueEntry(device, kind)
+	if err != nil {
+		fmt.Printf("[%s] Warning: %v\n", name, err)
+	}
+
+	var req ripRequest
+	switch {
+	case entry != nil:
+		fmt.Printf("[%s] Using queued %s\n", name, entry.describe())
+		req = entry.ripRequest
+	case kind == discKindMovie && w.category != "":
+		req = ripRequest{Type: discKindMovie, Category: w.category}
+	case kind == discKindTV:
+		fmt.Printf("[%s] No queued show for this TV disc; add one with rip watch queue add --show ... and reinsert the disc\n", name)
+		return
+	default:
+		fmt.Printf("[%s] Nothing queued for this disc; skipping it\n", name)
+		return
+	}
+	req.Device = device
+
+	args, err := ripJobArgs(req)
+	if err != nil {
+		fmt.Printf("[%s] Error: %v\n", name, err)
+		return
+	}
+	fmt.Printf("[%s] Running: %s\n", name, commandLine("rip", args...))
+	job.Finish()
+
+	lastPercent := -10.0
+	err = runner.Stream(w.executable, args, func(line string) {
+		if e, ok := parseProgressLine(line); ok {
+			// Report progress in 10% steps; the full output is too noisy with several drives
+			if e.Percent >= lastPercent+10 {
+				lastPercent = e.Percent
+				fmt.Printf("[%s] %.0f%% %s\n", name, e.Percent, progressLabel(e))
+			}
+			return
+		}
+		fmt.Printf("[%s] %s\n", name, line)
+	})
+	if err != nil {
+		fmt.Printf("[%s] Rip failed: %v\n", name, err)
+		return
+	}
+	fmt.Printf("[%s] Rip finished\n", name)
+}
+
+// classifyDisc guesses from the title durations whether a disc holds a movie or TV episodes.
+// TV discs have several titles of similar episode length (tv.min_episode_length to
+// tv.max_episode_length), often together with a "Play All" title about as long as all of them
+// combined. Movie discs have one feature-length title (at least movie.min_length, and longer than
+// an episode) and, at most, a few shorter extras.
+//
+// Returns the disc kind and a short explanation for the log.
+func classifyDisc(disc *DiscInfo) (kind, reason string) {
+	var episodes []time.Duration
+	var episodeTotal, longest time.Duration
+	for _, t := range disc.Titles {
+		if t.Duration >= AppConfig.TV.MinEpisodeLength && t.Duration <= AppConfig.TV.MaxEpisodeLength {
+			episodes = append(episodes, t.Duration)
+			episodeTotal += t.Duration
+		}
+		if t.Duration > longest {
+			longest = t.Duration
+		}
+	}
+	feature := longest >= AppConfig.Movie.MinLength && longest > AppConfig.TV.MaxEpisodeLength
+
+	// Several episode-length titles of similar length are episodes, unless a feature-length
+	// title exists that is not just all of them played back to back
+	if len(episodes) >= 3 && similarDurations(episodes) {
+		playAll := feature && longest >= episodeTotal*8/10 && longest <= episodeTotal*12/10
+		if !feature || playAll {
+			return discKindTV, fmt.Sprintf("%d titles of episode length", len(episodes))
+		}
+	}
+	if feature {
+		return discKindMovie, fmt.Sprintf("longest title is %s", formatDuration(longest))
+	}
+	if len(episodes) >= 2 {
+		return discKindTV, fmt.Sprintf("%d titles of episode length and no feature-length title", len(episodes))
+	}
+	return discKindUnknown, fmt.Sprintf("longest title is %s", formatDuration(longest))
+}
+
+// similarDurations reports whether most durations are within 25% of their median.
+func similarDurations(durations []time.Duration) bool {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	median := sorted[len(sorted)/2]
+	near := 0
+	for _, d := range durations {
+		if d >= median*3/4 && d <= median*5/4 {
+			near++
+		}
+	}
+	return near*4 >= len(durations)*3
+}
+
+// driveLabel returns a short name for a drive in log messages, such as "sr0".
+func driveLabel(d DriveInfo) string {
+	if d.Device != "" {
+		return strings.TrimPrefix(d.Device, "/dev/")
+	}
+	return fmt.Sprintf("disc:%d", d.Index)
+}
+
+// firstError returns the first non-nil error, or nil.
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// init registers the watch command with the root command and configures its flags.
+func init() {
+	watchCmd.Flags().StringP("category", "c", "", "Category folder for movies that have no queue entry (default: skip them)")
+	watchCmd.Flags().Duration("interval", 5*time.Second, "How often to check the drives for new discs")
+	watchCmd.Flags().Bool("once", false, "Rip the discs already in the drives in parallel, then exit")
+
+	rootCmd.AddCommand(watchCmd)
+}
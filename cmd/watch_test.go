@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWatchRipsEveryDriveAndSkipsDrivesInUse(t *testing.T) {
+	env := newRipEnv(t)
+	AppConfig = defaultConfig()
+	env.Fake.On("makemkvcon", "-r", "--cache=1", "info").Return(`DRV:0,2,999,1,"DVD drive","HEAT","/dev/sr0"
+DRV:1,2,999,1,"DVD drive","ANCHORMAN","/dev/sr1"
+DRV:2,2,999,1,"DVD drive","BREAKUP","/dev/sr2"
+DRV:3,0,999,0,"DVD drive","","/dev/sr3"
+`, nil)
+	env.Fake.On("makemkvcon", "-r", "info").Return(robotInfo("1:45:43", "0:04:12"), nil)
+	env.Fake.On("/usr/local/bin/rip").Return("", nil)
+
+	// A rip started by hand owns sr1
+	job, err := startJob("/dev/sr1", "dvd", "Anchorman")
+	if err != nil {
+		t.Fatalf("startJob: %v", err)
+	}
+	defer job.Finish()
+
+	w := &watcher{category: "Movies", executable: "/usr/local/bin/rip", handled: map[int]bool{}}
+	w.poll()
+	w.wg.Wait()
+
+	var ripped []string
+	for _, call := range env.Fake.Calls {
+		if call[0] == w.executable {
+			i := slices.Index(call, "--device")
+			ripped = append(ripped, call[i+1])
+		}
+	}
+	slices.Sort(ripped)
+	if want := []string{"/dev/sr0", "/dev/sr2"}; !slices.Equal(ripped, want) {
+		t.Errorf("ripped %q, want %q", ripped, want)
+	}
+	if env.Fake.Called("makemkvcon", "-r", "info", "disc:1") != 0 {
+		t.Error("read the disc in a drive another rip is using")
+	}
+	if !w.handled[1] {
+		t.Error("drive in use not marked handled; its disc would be ripped once the other rip ends")
+	}
+
+	// The next poll starts nothing new while the discs are still in the drives
+	calls := len(env.Fake.Calls)
+	w.poll()
+	w.wg.Wait()
+	if len(env.Fake.Calls) != calls+1 {
+		t.Errorf("second poll ran %q, want only the drive listing", env.Fake.Calls[calls:])
+	}
+}
+
+func TestClassifyDisc(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     []string // Config overrides
+		lengths []string
+		want    string
+	}{
+		{"movie with extras", nil, []string{"1:52:10", "0:04:12", "0:12:30", "0:03:01"}, discKindMovie},
+		{"episodes", nil, []string{"0:22:31", "0:23:02", "0:22:48", "0:21:55"}, discKindTV},
+		{"episodes with Play All", nil, []string{"1:30:16", "0:22:31", "0:23:02", "0:22:48", "0:21:55"}, discKindTV},
+		{"hour-long episodes", nil, []string{"0:58:31", "1:02:02", "0:59:48"}, discKindTV},
+		{"two episodes", nil, []string{"0:44:31", "0:43:02", "0:02:00"}, discKindTV},
+		{"extras only", nil, []string{"0:04:31", "0:03:02", "0:02:00"}, discKindUnknown},
+		{"short episodes below tv.min_episode_length", nil, []string{"0:07:31", "0:07:02", "0:07:48"}, discKindUnknown},
+		{"short episodes with a lower tv.min_episode_length", []string{"tv.min_episode_length=5m"}, []string{"0:07:31", "0:07:02", "0:07:48"}, discKindTV},
+		{"long episodes with a higher tv.max_episode_length", []string{"tv.max_episode_length=95m"}, []string{"1:25:31", "1:28:02", "1:26:48"}, discKindTV},
+		{"short movie with a lower movie.min_length", []string{"movie.min_length=40m", "tv.max_episode_length=35m"}, []string{"0:48:10", "0:04:12"}, discKindMovie},
+		{"short movie", nil, []string{"0:48:10", "0:04:12"}, discKindUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AppConfig = defaultConfig()
+			for _, setting := range tt.set {
+				key, value, _ := strings.Cut(setting, "=")
+				if err := AppConfig.override(key, value, configSourceFlag); err != nil {
+					t.Fatal(err)
+				}
+			}
+			disc, err := ParseMakeMKVInfo(strings.NewReader(robotInfo(tt.lengths...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kind, reason := classifyDisc(disc); kind != tt.want {
+				t.Errorf("classifyDisc(%v) = %q (%s), want %q", tt.lengths, kind, reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchHandleReadsDiscUnderDriveLock(t *testing.T) {
+	env := newRipEnv(t)
+	AppConfig = defaultConfig()
+	var lockedDuringRead bool
+	env.Fake.On("makemkvcon", "-r", "info").Do(func(args []string) error {
+		lockedDuringRead = driveInUse("/dev/sr0")
+		return nil
+	}).Return(robotInfo("1:45:43", "0:12:00"), nil)
+	env.Fake.On("/usr/local/bin/rip").Do(func(args []string) error {
+		if driveInUse("/dev/sr0") {
+			t.Error("drive still locked by rip watch when the rip started")
+		}
+		return nil
+	}).Return("", nil)
+
+	w := &watcher{category: "Movies", executable: "/usr/local/bin/rip", handled: map[int]bool{}}
+	w.handle(DriveInfo{Index: 0, Device: "/dev/sr0", DiscName: "HEAT"})
+
+	// The read uses the shorter of tv.min_episode_length and movie.min_length
+	if got, want := findCall(env.Fake, "makemkvcon", "-r", "info"), []string{"makemkvcon", "-r", "info", "disc:0", "--minlength=600"}; !slices.Equal(got, want) {
+		t.Errorf("info command = %q, want %q", got, want)
+	}
+	if !lockedDuringRead {
+		t.Error("disc read without holding the drive lock")
+	}
+	if env.Fake.Called("/usr/local/bin/rip") != 1 {
+		t.Errorf("rip ran %d times, want once", env.Fake.Called("/usr/local/bin/rip"))
+	}
+	if driveInUse("/dev/sr0") {
+		t.Error("drive lock not released")
+	}
+
+	// A rip started by hand after the poll keeps the drive: the disc is not read or ripped
+	job, err := startJob("/dev/sr0", "dvd", "Heat")
+	if err != nil {
+		t.Fatalf("startJob: %v", err)
+	}
+	defer job.Finish()
+	calls := len(env.Fake.Calls)
+	w.handle(DriveInfo{Index: 0, Device: "/dev/sr0", DiscName: "HEAT"})
+	if len(env.Fake.Calls) != calls {
+		t.Errorf("handled a drive another rip holds: ran %q", env.Fake.Calls[calls:])
+	}
+}
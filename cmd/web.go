@@ -0,0 +1,529 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// webCmd represents the `web` command that runs the rip web daemon.
+// The daemon serves a small REST API for listing devices, managing categories
+// and starting rip jobs in the background using the rip CLI itself.
+var webCmd = &cobra.Command{
+	Use:   "web",
+	Short: "Run the rip web daemon",
+	Long: `Runs the rip web daemon, which serves a JSON API for managing rips over HTTP.
+Rip jobs are started in the background by running the rip CLI, so the daemon
+uses exactly the same dvd and tv workflows as the command line.
+
+The API has no authentication, so it listens on 127.0.0.1 unless --listen is given.
+
+See WEB_INTERFACE.md for the full list of API endpoints.`,
+	Args: cobra.NoArgs,
+	Run:  runWeb,
+}
+
+// maxJobOutput is the maximum number of bytes of rip output kept for each job.
+const maxJobOutput = 64 * 1024
+
+// ripJob describes a single background rip started by the web daemon.
+type ripJob struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Device     string     `json:"device"`
+	Category   string     `json:"category,omitempty"`
+	Movie      string     `json:"movie,omitempty"`
+	Show       string     `json:"show,omitempty"`
+	SeasonDisc string     `json:"seasonDisc,omitempty"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Output     string     `json:"output,omitempty"`
+
+	// Progress is the latest progress event reported by the rip, if any
+	Progress *ProgressEvent `json:"progress,omitempty"`
+
+	output *tailBuffer
+}
+
+// ripRequest is the JSON body accepted by POST /api/rip.
+// Type defaults to "dvd"; TV rips set Type to "tv" and provide Show and SeasonDisc.
+type ripRequest struct {
+	Type       string `json:"type"`
+	Device     string `json:"device"`
+	Category   string `json:"category"`
+	Movie      string `json:"movie"`
+	Show       string `json:"show"`
+	SeasonDisc string `json:"seasonDisc"`
+	TMDBID     int    `json:"tmdbId"`
+}
+
+// webServer holds the state of the running web daemon.
+type webServer struct {
+	storagePath string
+	ripCommand  string
+
+	mu     sync.Mutex
+	jobs   []*ripJob
+	nextID int
+}
+
+// runWeb starts the web daemon and blocks until the HTTP server exits.
+func runWeb(cmd *cobra.Command, _ []string) {
+	port, _ := cmd.Flags().GetInt("port")
+	listen, _ := cmd.Flags().GetString("listen")
+	ripCommand, _ := cmd.Flags().GetString("rip")
+
+	// The global --storage flag overrides storage_path here and in the rips started for jobs
+	storage := AppConfig.StoragePath
+
+	if err := VerifyStoragePath(storage); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	srv := &webServer{
+		storagePath: storage,
+		ripCommand:  ripCommand,
+	}
+
+	// The API has no authentication, so it only listens on this machine unless --listen says otherwise
+	addr := net.JoinHostPort(listen, strconv.Itoa(port))
+	fmt.Printf("rip web daemon listening on http://%s\n", addr)
+	if ip := net.ParseIP(listen); listen == "" || ip == nil || !ip.IsLoopback() {
+		fmt.Println("Warning: the API has no authentication; anyone who can reach this address can start rips and change categories")
+	}
+	fmt.Printf("Storage path: %s\n", storage)
+	if err := http.ListenAndServe(addr, srv.routes()); err != nil {
+		log.Fatalf("Error running web server: %v", err)
+	}
+}
+
+// routes registers all API handlers and returns the server's handler.
+func (s *webServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/devices", s.handleDevices)
+	mux.HandleFunc("GET /api/categories", s.handleListCategories)
+	mux.HandleFunc("POST /api/categories", s.handleCreateCategory)
+	mux.HandleFunc("PUT /api/categories", s.handleRenameCategory)
+	mux.HandleFunc("DELETE /api/categories", s.handleDeleteCategory)
+	mux.HandleFunc("POST /api/rip", s.handleStartRip)
+	mux.HandleFunc("GET /api/jobs", s.handleListJobs)
+	mux.HandleFunc("GET /api/jobs/{id}", s.handleGetJob)
+	mux.HandleFunc("GET /api/status", s.handleStatus)
+	return mux
+}
+
+// handleDevices returns the optical drives found on this machine.
+func (s *webServer) handleDevices(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"devices": findOpticalDevices()})
+}
+
+// handleListCategories returns every category directory under the storage path.
+func (s *webServer) handleListCategories(w http.ResponseWriter, _ *http.Request) {
+	categories, err := listCategories(s.storagePath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"categories": categories})
+}
+
+// handleCreateCategory creates a new category directory.
+func (s *webServer) handleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+	if err := validateCategoryName(req.Name); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.storagePath, req.Name), 0755); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("error creating category: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"name": req.Name})
+}
+
+// handleRenameCategory renames an existing category directory.
+func (s *webServer) handleRenameCategory(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		OldName string `json:"oldName"`
+		NewName string `json:"newName"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+	for _, name := range []string{req.OldName, req.NewName} {
+		if err := validateCategoryName(name); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	oldPath := filepath.Join(s.storagePath, req.OldName)
+	newPath := filepath.Join(s.storagePath, req.NewName)
+	if _, err := os.Stat(oldPath); err != nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("category not found: %s", req.OldName))
+		return
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("category already exists: %s", req.NewName))
+		return
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("error renaming category: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"name": req.NewName})
+}
+
+// handleDeleteCategory removes a category directory.
+// Only empty categories are removed so that ripped media is never deleted from the web UI.
+func (s *webServer) handleDeleteCategory(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+	if err := validateCategoryName(req.Name); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	path := filepath.Join(s.storagePath, req.Name)
+	if _, err := os.Stat(path); err != nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("category not found: %s", req.Name))
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("category %s is not empty", req.Name))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"name": req.Name})
+}
+
+// handleStartRip validates a rip request and starts it as a background job.
+func (s *webServer) handleStartRip(w http.ResponseWriter, r *http.Request) {
+	var req ripRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+	if req.Type == "" {
+		req.Type = "dvd"
+	}
+	if req.Device == "" {
+		req.Device = AppConfig.Device
+	}
+
+	args, err := ripJobArgs(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	job, err := s.startJob(req, args)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	s.mu.Lock()
+	snap := job.snapshot()
+	s.mu.Unlock()
+	writeJSON(w, http.StatusAccepted, snap)
+}
+
+// handleListJobs returns all jobs started since the daemon was launched.
+func (s *webServer) handleListJobs(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	jobs := make([]ripJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		snap := job.snapshot()
+		snap.Output = ""
+		jobs = append(jobs, snap)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{"jobs": jobs})
+}
+
+// handleGetJob returns a single job including the tail of its output.
+func (s *webServer) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.jobs {
+		if job.ID == id {
+			writeJSON(w, http.StatusOK, job.snapshot())
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("job not found: %s", id))
+}
+
+// handleStatus returns the daemon status, including every rip running on the machine
+// (not only the daemon's own jobs) and the drive each one is using.
+func (s *webServer) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	running, err := runningJobs()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if running == nil {
+		running = []JobInfo{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":           "running",
+		"storagePath":      s.storagePath,
+		"ripCommand":       s.ripCommand,
+		"availableDevices": findOpticalDevices(),
+		"runningRips":      running,
+	})
+}
+
+// ripJobArgs converts a rip request into the rip CLI arguments for the job.
+// Jobs cannot answer prompts, so the best metadata match is used unless a TMDB ID is given.
+// The global config flags of this rip are passed on, so the job uses the same configuration.
+// Values from the request are only passed as flag values or after "--", so a show name such as
+// "--storage=/etc" cannot be read as a flag by the job.
+func ripJobArgs(req ripRequest) ([]string, error) {
+	match := []string{"--yes"}
+	if req.TMDBID > 0 {
+		match = []string{"--tmdb-id", strconv.Itoa(req.TMDBID)}
+	}
+
+	switch req.Type {
+	case "dvd":
+		if err := validateCategoryName(req.Category); err != nil {
+			return nil, err
+		}
+		args := []string{"dvd", "--device", req.Device, "--category", req.Category, "--progress", "json"}
+		if req.Movie != "" {
+			args = append(args, "--movie", req.Movie)
+		}
+		return append(append(args, match...), configFlagArgs()...), nil
+	case "tv":
+		if req.Show == "" {
+			return nil, fmt.Errorf("show name must be provided for tv rips")
+		}
+		if !regexp.MustCompile(`^\d+-\d+$`).MatchString(req.SeasonDisc) {
+			return nil, fmt.Errorf("seasonDisc must use the season-disc format (e.g. 1-2)")
+		}
+		args := []string{"tv", "--device", req.Device, "--progress", "json"}
+		args = append(append(args, match...), configFlagArgs()...)
+		return append(args, "--", req.Show, req.SeasonDisc), nil
+	default:
+		return nil, fmt.Errorf("unknown rip type: %s", req.Type)
+	}
+}
+
+// startJob registers a new job and runs the rip CLI for it in the background.
+// Only one job may use a device at a time, including rips started outside the daemon.
+func (s *webServer) startJob(req ripRequest, args []string) (*ripJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.Device == req.Device && job.Status == "running" {
+			return nil, fmt.Errorf("device %s is busy with job %s", req.Device, job.ID)
+		}
+	}
+	if running, err := runningJobs(); err == nil {
+		for _, r := range running {
+			if r.Device == req.Device {
+				return nil, fmt.Errorf("device %s is busy with rip %s (pid %d)", req.Device, r.ID, r.PID)
+			}
+		}
+	}
+
+	s.nextID++
+	job := &ripJob{
+		ID:         fmt.Sprintf("%d", s.nextID),
+		Type:       req.Type,
+		Device:     req.Device,
+		Category:   req.Category,
+		Movie:      req.Movie,
+		Show:       req.Show,
+		SeasonDisc: req.SeasonDisc,
+		Status:     "running",
+		StartedAt:  time.Now(),
+		output:     &tailBuffer{max: maxJobOutput},
+	}
+	s.jobs = append(s.jobs, job)
+
+	go s.runJob(job, args)
+	return job, nil
+}
+
+// runJob runs the rip CLI for a job and records the result when it exits.
+func (s *webServer) runJob(job *ripJob, args []string) {
+	fmt.Printf("Job %s: %s %s\n", job.ID, s.ripCommand, strings.Join(args, " "))
+
+	// Progress lines from the rip are turned into job progress; everything else is kept as output
+	err := runner.Stream(s.ripCommand, args, func(line string) {
+		if e, ok := parseProgressLine(line); ok {
+			s.mu.Lock()
+			job.Progress = &e
+			s.mu.Unlock()
+			return
+		}
+		fmt.Fprintln(job.output, line)
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := time.Now()
+	job.FinishedAt = &finished
+	if err != nil {
+		job.Status = "failed"
+		job.Error = err.Error()
+		fmt.Printf("Job %s failed: %v\n", job.ID, err)
+		return
+	}
+	job.Status = "completed"
+	fmt.Printf("Job %s completed\n", job.ID)
+}
+
+// snapshot returns a copy of the job that is safe to encode while the job is running.
+// Callers must hold the server mutex.
+func (j *ripJob) snapshot() ripJob {
+	snap := *j
+	snap.Output = j.output.String()
+	snap.output = nil
+	if j.Progress != nil {
+		progress := *j.Progress
+		snap.Progress = &progress
+	}
+	return snap
+}
+
+// tailBuffer is an io.Writer that keeps only the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	max int
+}
+
+// Write appends p to the buffer, discarding the oldest bytes beyond the limit.
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf.Write(p)
+	if over := t.buf.Len() - t.max; over > 0 {
+		t.buf.Next(over)
+	}
+	return len(p), nil
+}
+
+// String returns the buffered output.
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.buf.String()
+}
+
+// findOpticalDevices returns the optical drive device paths on this machine.
+// On Linux these are the /dev/sr* devices; on macOS the disks reported by drutil.
+func findOpticalDevices() []string {
+	devices := []string{}
+
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := runner.Run("drutil", "status")
+		if err != nil {
+			return devices
+		}
+		// drutil reports the block device (e.g. /dev/disk6); MakeMKV wants the raw device
+		re := regexp.MustCompile(`/dev/disk(\d+)`)
+		for _, m := range re.FindAllStringSubmatch(out, -1) {
+			devices = append(devices, "/dev/rdisk"+m[1])
+		}
+	default:
+		matches, _ := filepath.Glob("/dev/sr[0-9]*")
+		devices = append(devices, matches...)
+	}
+
+	sort.Strings(devices)
+	return devices
+}
+
+// listCategories returns the names of all non-hidden directories in storagePath.
+func listCategories(storagePath string) ([]string, error) {
+	entries, err := os.ReadDir(storagePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading storage path: %v", err)
+	}
+
+	categories := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			categories = append(categories, entry.Name())
+		}
+	}
+	return categories, nil
+}
+
+// validateCategoryName rejects category names that are empty, hidden, contain path separators
+// or are changed by the paths rules (see cleanName), so a request can never reach outside the
+// storage path or create a folder the library's filesystem cannot hold.
+func validateCategoryName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("category name must be provided")
+	}
+	if strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid category name: %s", name)
+	}
+	if clean := AppConfig.Paths.cleanName(name, 0); clean != name {
+		return fmt.Errorf("invalid category name: %s (try %s)", name, clean)
+	}
+	return nil
+}
+
+// writeJSON encodes v as the JSON response body with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// writeError writes an error response in the form {"error": "..."}.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// init registers the web command with the root command and configures its flags.
+func init() {
+	webCmd.Flags().IntP("port", "p", 8080, "Port to run the web server on")
+	webCmd.Flags().String("listen", "127.0.0.1", "Address to listen on (0.0.0.0 for every interface; the API has no authentication)")
+	webCmd.Flags().String("rip", "rip", "Path to the rip CLI command (if not in PATH)")
+
+	// Register the web command as a subcommand of the root command
+	rootCmd.AddCommand(webCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// jobFlags returns a flag set with the flags ripJobArgs passes, to parse the job arguments the way
+// the rip started for the job would.
+func jobFlags() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("rip", pflag.ContinueOnError)
+	for _, name := range []string{"device", "category", "movie", "progress", "tmdb-id", "config", "storage", "profile"} {
+		fs.String(name, "", "")
+	}
+	fs.StringArray("set", nil, "")
+	fs.Bool("yes", false, "")
+	return fs
+}
+
+func TestRipJobArgsKeepsRequestValuesOutOfFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		req  ripRequest
+		args []string // Positional arguments the job must see
+	}{
+		{
+			name: "tv show that looks like a flag",
+			req:  ripRequest{Type: "tv", Device: "/dev/sr0", Show: "--storage=/etc", SeasonDisc: "1-2"},
+			args: []string{"--storage=/etc", "1-2"},
+		},
+		{
+			name: "tv show that looks like the config flag",
+			req:  ripRequest{Type: "tv", Device: "/dev/sr0", Show: "--config=/tmp/x.yaml", SeasonDisc: "3-1"},
+			args: []string{"--config=/tmp/x.yaml", "3-1"},
+		},
+		{
+			name: "movie that looks like a flag",
+			req:  ripRequest{Type: "dvd", Device: "/dev/sr0", Category: "Drama", Movie: "--storage=/etc"},
+		},
+	}
+	AppConfig = defaultConfig()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := ripJobArgs(tt.req)
+			if err != nil {
+				t.Fatalf("ripJobArgs: %v", err)
+			}
+			fs := jobFlags()
+			if err := fs.Parse(args[1:]); err != nil {
+				t.Fatalf("parsing %q: %v", args, err)
+			}
+			if got, _ := fs.GetString("storage"); got != "" {
+				t.Errorf("--storage = %q, want it unset (args %q)", got, args)
+			}
+			if got, _ := fs.GetString("config"); got != "" {
+				t.Errorf("--config = %q, want it unset (args %q)", got, args)
+			}
+			if !slices.Equal(fs.Args(), tt.args) {
+				t.Errorf("positional arguments = %q, want %q", fs.Args(), tt.args)
+			}
+			if tt.req.Movie != "" {
+				if got, _ := fs.GetString("movie"); got != tt.req.Movie {
+					t.Errorf("--movie = %q, want %q", got, tt.req.Movie)
+				}
+			}
+		})
+	}
+}
+
+func TestRipJobArgsRejectsBadRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		req  ripRequest
+	}{
+		{"unknown type", ripRequest{Type: "bluray", Device: "/dev/sr0"}},
+		{"tv without show", ripRequest{Type: "tv", Device: "/dev/sr0", SeasonDisc: "1-1"}},
+		{"tv with bad season-disc", ripRequest{Type: "tv", Device: "/dev/sr0", Show: "The Office", SeasonDisc: "1-2; rm"}},
+		{"dvd with path category", ripRequest{Type: "dvd", Device: "/dev/sr0", Category: "../etc"}},
+	}
+	AppConfig = defaultConfig()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if args, err := ripJobArgs(tt.req); err == nil {
+				t.Errorf("ripJobArgs(%+v) = %q, want an error", tt.req, args)
+			}
+		})
+	}
+}
@@ -3,25 +3,18 @@ module github.com/rmasci/dvdrip
 go 1.25.0
 
 require (
-	github.com/rmasci/script v1.0.0-rmasci
+	github.com/briandowns/spinner v1.23.2
 	github.com/spf13/cobra v1.10.2
+	github.com/spf13/pflag v1.0.9
+	golang.org/x/text v0.30.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/briandowns/spinner v1.23.2 // indirect
 	github.com/fatih/color v1.7.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/itchyny/gojq v0.12.13 // indirect
-	github.com/itchyny/timefmt-go v0.1.5 // indirect
 	github.com/mattn/go-colorable v0.1.2 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
-	github.com/mattn/go-runewidth v0.0.15 // indirect
-	github.com/rivo/uniseg v0.4.4 // indirect
-	github.com/rmasci/csvtable v0.7.0 // indirect
-	github.com/rmasci/gotabulate v1.2.8 // indirect
-	github.com/spf13/pflag v1.0.9 // indirect
-	github.com/tealeg/xlsx v1.0.5 // indirect
 	golang.org/x/sys v0.10.0 // indirect
 	golang.org/x/term v0.8.0 // indirect
-	mvdan.cc/sh/v3 v3.7.0 // indirect
 )